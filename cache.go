@@ -0,0 +1,147 @@
+package manager
+
+import (
+	"sync"
+	"time"
+)
+
+// CacheOptions enables the read-through cache of the listing calls. A zero TTL disables the cache
+// of the resource. The sessions of a session pool share the cache. It is invalidated by every mutating
+// call of the same session or session pool, changes made by other sessions or the QTS UI become
+// visible after the TTL at the latest.
+// The returned objects are shared with the cache and must not be modified.
+type CacheOptions struct {
+	StoragePoolsTTL time.Duration // GetStoragePools, which requires one request per pool
+	LUNsTTL         time.Duration // GetLUNs, GetLUNByIndex is never cached
+	ISCSITargetsTTL time.Duration // GetISCSITargets
+}
+
+// responseCache caches the results of the listing calls. All methods are no-ops on a nil cache.
+type responseCache struct {
+	options CacheOptions
+
+	lock       sync.Mutex
+	generation uint64 // incremented on invalidation, to discard results of requests started before
+	pools      cacheEntry[*StoragePool]
+	luns       cacheEntry[*LUN]
+	targets    cacheEntry[*ISCSITarget]
+}
+
+type cacheEntry[T any] struct {
+	value   []T
+	valid   bool // an empty list is cached as well
+	expires time.Time
+}
+
+func newResponseCache(options *CacheOptions) *responseCache {
+	if options == nil {
+		return nil
+	}
+	return &responseCache{options: *options}
+}
+
+// cacheGet returns a copy of the cached list, false if missing or expired.
+func cacheGet[T any](c *responseCache, entry *cacheEntry[T]) ([]T, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !entry.valid || time.Now().After(entry.expires) {
+		return nil, false
+	}
+
+	return append([]T(nil), entry.value...), true
+}
+
+// cachePut stores a copy of the list, unless the cache has been invalidated since the request started.
+func cachePut[T any](c *responseCache, entry *cacheEntry[T], generation uint64, ttl time.Duration, value []T) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.generation != generation || ttl <= 0 {
+		return
+	}
+
+	entry.value = append([]T(nil), value...)
+	entry.valid = true
+	entry.expires = time.Now().Add(ttl)
+}
+
+// start returns the generation to pass to the put methods.
+func (c *responseCache) start() uint64 {
+	if c == nil {
+		return 0
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.generation
+}
+
+func (c *responseCache) invalidate() {
+	if c == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.generation++
+	c.pools = cacheEntry[*StoragePool]{}
+	c.luns = cacheEntry[*LUN]{}
+	c.targets = cacheEntry[*ISCSITarget]{}
+}
+
+func (c *responseCache) getStoragePools() ([]*StoragePool, bool) {
+	if c == nil {
+		return nil, false
+	}
+	return cacheGet(c, &c.pools)
+}
+
+func (c *responseCache) putStoragePools(generation uint64, pools []*StoragePool) {
+	if c == nil {
+		return
+	}
+	cachePut(c, &c.pools, generation, c.options.StoragePoolsTTL, pools)
+}
+
+func (c *responseCache) getLUNs() ([]*LUN, bool) {
+	if c == nil {
+		return nil, false
+	}
+	return cacheGet(c, &c.luns)
+}
+
+func (c *responseCache) putLUNs(generation uint64, luns []*LUN) {
+	if c == nil {
+		return
+	}
+	cachePut(c, &c.luns, generation, c.options.LUNsTTL, luns)
+}
+
+func (c *responseCache) getISCSITargets() ([]*ISCSITarget, bool) {
+	if c == nil {
+		return nil, false
+	}
+	return cacheGet(c, &c.targets)
+}
+
+func (c *responseCache) putISCSITargets(generation uint64, targets []*ISCSITarget) {
+	if c == nil {
+		return
+	}
+	cachePut(c, &c.targets, generation, c.options.ISCSITargetsTTL, targets)
+}
+
+// InvalidateCache discards all cached results, e.g. after changes made through the QTS UI.
+// It is a no-op if the cache is not enabled.
+func (s *QnapSession) InvalidateCache() {
+	s.cache.invalidate()
+}
+
+// InvalidateCache discards all cached results of the pool's sessions, e.g. after changes made through the QTS UI.
+// It is a no-op if the cache is not enabled.
+func (p *SessionPool) InvalidateCache() {
+	p.shared.cache.invalidate()
+}
@@ -0,0 +1,79 @@
+package manager_test
+
+import (
+	"testing"
+	"time"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+	"github.com/nine-lives-later/go-qnap-disk-manager/qnapfake"
+)
+
+func TestCache(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	s, err := manager.Connect(fake.URL, "admin", "admin", &manager.ConfigOptions{
+		APICallTimeout: 5 * time.Second,
+		Cache: &manager.CacheOptions{
+			StoragePoolsTTL: time.Hour,
+			LUNsTTL:         time.Hour,
+			ISCSITargetsTTL: time.Hour,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer s.Close()
+
+	other := connectFake(t, fake)
+
+	// fill the cache
+	if luns, err := s.GetLUNs(); err != nil || len(luns) != 0 {
+		t.Fatalf("Unexpected LUNs: %v, %v", luns, err)
+	}
+	if pools, err := s.GetStoragePools(); err != nil || len(pools) != 1 {
+		t.Fatalf("Unexpected storage pools: %v, %v", pools, err)
+	}
+
+	// changes of other sessions are not visible
+	lun, err := other.CreateBlockBasedLUN(1, "data01", 1, manager.LUNAllocateMode_Thin, false, 80)
+	if err != nil {
+		t.Fatalf("Failed to create LUN: %v", err)
+	}
+	fake.AddStoragePool(2, 1<<40)
+
+	if luns, _ := s.GetLUNs(); len(luns) != 0 {
+		t.Fatalf("Cached LUNs expected: %v", luns)
+	}
+	if pools, _ := s.GetStoragePools(); len(pools) != 1 {
+		t.Fatalf("Cached storage pools expected: %v", pools)
+	}
+
+	// explicit invalidation
+	s.InvalidateCache()
+
+	if luns, _ := s.GetLUNs(); len(luns) != 1 {
+		t.Fatalf("Refreshed LUNs expected: %v", luns)
+	}
+	if pools, _ := s.GetStoragePools(); len(pools) != 2 {
+		t.Fatalf("Refreshed storage pools expected: %v", pools)
+	}
+
+	// automatic invalidation by mutations
+	if targets, _ := s.GetISCSITargets(); len(targets) != 0 {
+		t.Fatalf("Unexpected targets: %v", targets)
+	}
+	if _, err := s.CreateISCSITarget("k8s", ""); err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+	if targets, _ := s.GetISCSITargets(); len(targets) != 1 {
+		t.Fatalf("Refreshed targets expected: %v", targets)
+	}
+
+	if err := s.DeleteLUN(lun.LUNIndex); err != nil {
+		t.Fatalf("Failed to delete LUN: %v", err)
+	}
+	if luns, _ := s.GetLUNs(); len(luns) != 0 {
+		t.Fatalf("Refreshed LUNs expected: %v", luns)
+	}
+}
@@ -0,0 +1,50 @@
+package manager
+
+// Capabilities describes the API parameters to use for a specific firmware generation.
+type Capabilities struct {
+	OSFlavor     OSFlavor
+	ISCSIProduct string // value of the "prod" parameter of the iSCSI calls
+	ISCSITarget  string // value of the "target" parameter of the iSCSI calls
+	ISCSIBackend string // value of the "backend" parameter of the iSCSI calls
+}
+
+// defaultCapabilities is used when the firmware version is unknown.
+var defaultCapabilities = Capabilities{
+	OSFlavor:     OSFlavor_QTS,
+	ISCSIProduct: "qts",
+	ISCSITarget:  "lio",
+	ISCSIBackend: "dm",
+}
+
+// capabilityTable lists the capabilities per firmware generation.
+// The first entry matching the OS flavor with a minimum version lower or equal wins.
+// Firmware without an entry uses the default capabilities.
+// Set ConfigOptions.Capabilities to override the parameters, e.g. for untested firmware.
+var capabilityTable = []struct {
+	OSFlavor     OSFlavor
+	MinMajor     int
+	MinMinor     int
+	Capabilities Capabilities
+}{
+	{OSFlavor_QuTSHero, 0, 0, Capabilities{OSFlavor: OSFlavor_QuTSHero, ISCSIProduct: "qts", ISCSITarget: "lio", ISCSIBackend: "zfs"}},
+}
+
+// lookupCapabilities returns the capabilities for the firmware version.
+func lookupCapabilities(firmwareVersion string) Capabilities {
+	if firmwareVersion == "" {
+		return defaultCapabilities
+	}
+
+	flavor, major, minor := parseFirmwareVersion(firmwareVersion)
+
+	for _, entry := range capabilityTable {
+		if entry.OSFlavor != flavor {
+			continue
+		}
+		if major > entry.MinMajor || (major == entry.MinMajor && minor >= entry.MinMinor) {
+			return entry.Capabilities
+		}
+	}
+
+	return defaultCapabilities
+}
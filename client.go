@@ -0,0 +1,74 @@
+package manager
+
+import (
+	"context"
+)
+
+// Reader is the interface of all reading operations of the QNAP API.
+// It is implemented by QnapSession and SessionPool.
+type Reader interface {
+	GetSystemInfo() (*SystemInfo, error)
+	GetStoragePools() ([]*StoragePool, error)
+	GetLUNs() ([]*LUN, error)
+	GetLUNByIndex(lunIndex int) (*LUN, error)
+	GetISCSITargets() ([]*ISCSITarget, error)
+
+	GetSystemInfoContext(ctx context.Context) (*SystemInfo, error)
+	GetStoragePoolsContext(ctx context.Context) ([]*StoragePool, error)
+	GetLUNsContext(ctx context.Context) ([]*LUN, error)
+	GetLUNByIndexContext(ctx context.Context, lunIndex int) (*LUN, error)
+	GetISCSITargetsContext(ctx context.Context) ([]*ISCSITarget, error)
+}
+
+// Client is the interface of all public operations of the QNAP API.
+// It is implemented by QnapSession and allows to replace it by a mock, e.g. in unit tests.
+type Client interface {
+	Reader
+
+	Login(username, password string) error
+	LoginWithOTP(username, password, securityCode string) error
+	LoginWithToken(username, token string) error
+	Logout() error
+	Close() error
+
+	Username() string
+	Token() string
+	SessionID() string
+	NASHostname() string
+	IsAdmin() bool
+	SystemInfo() *SystemInfo
+	Capabilities() Capabilities
+	InvalidateCache()
+
+	GetStoragePoolsEx(options *StoragePoolOptions) ([]*StoragePool, error)
+	GetStoragePoolIDs() ([]int, error)
+	CreateBlockBasedLUN(storagePoolID int, name string, capacityGB int, allocateMode LUNAllocateMode, useSSDCache bool, alertThresoldPercent int) (*LUN, error)
+	CreateBlockBasedLUNEx(storagePoolID int, name string, capacityGB int, allocateMode LUNAllocateMode, useSSDCache bool, alertThresoldPercent int, zfsOptions *ZFSLUNOptions) (*LUN, error)
+	DeleteLUN(lunID int) error
+	WaitForLUNVolume(lunID int) (*LUN, error)
+	AssignLUN(lunIndex int, targetIndex int) error
+	UnassignLUN(lunIndex int, targetIndex int) error
+	AddLUNInitiator(lunIndex int, initiatorIQN string, accessMode int) error
+	CreateISCSITarget(name, alias string) (*ISCSITarget, error)
+	DeleteISCSITarget(targetIndex int) error
+	ExportISCSIConfig() (*ISCSIConfig, error)
+	ImportISCSIConfig(config *ISCSIConfig, options *ImportOptions) (*ImportResult, error)
+
+	GetStoragePoolsExContext(ctx context.Context, options *StoragePoolOptions) ([]*StoragePool, error)
+	GetStoragePoolIDsContext(ctx context.Context) ([]int, error)
+	CreateBlockBasedLUNContext(ctx context.Context, storagePoolID int, name string, capacityGB int, allocateMode LUNAllocateMode, useSSDCache bool, alertThresoldPercent int, zfsOptions *ZFSLUNOptions) (*LUN, error)
+	DeleteLUNContext(ctx context.Context, lunID int) error
+	WaitForLUNVolumeContext(ctx context.Context, lunID int) (*LUN, error)
+	AssignLUNContext(ctx context.Context, lunIndex int, targetIndex int) error
+	UnassignLUNContext(ctx context.Context, lunIndex int, targetIndex int) error
+	AddLUNInitiatorContext(ctx context.Context, lunIndex int, initiatorIQN string, accessMode int) error
+	CreateISCSITargetContext(ctx context.Context, name, alias string) (*ISCSITarget, error)
+	DeleteISCSITargetContext(ctx context.Context, targetIndex int) error
+	ExportISCSIConfigContext(ctx context.Context) (*ISCSIConfig, error)
+	ImportISCSIConfigContext(ctx context.Context, config *ISCSIConfig, options *ImportOptions) (*ImportResult, error)
+}
+
+var (
+	_ Client = (*QnapSession)(nil)
+	_ Reader = (*SessionPool)(nil)
+)
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+	"github.com/nine-lives-later/go-qnap-disk-manager/metrics"
+)
+
+// cachedCollector refreshes the storage information of a QNAP system in the background
+// and serves the scrapes from the cache.
+type cachedCollector struct {
+	host        string
+	credentials manager.CredentialProvider
+	options     *manager.ConfigOptions
+	logger      *slog.Logger
+
+	session *manager.QnapSession // only used by the refresh loop
+
+	lock        sync.RWMutex
+	up          bool
+	lastRefresh time.Time
+	pools       []*manager.StoragePool
+	luns        []*manager.LUN
+	targets     []*manager.ISCSITarget
+}
+
+var lastRefreshDesc = prometheus.NewDesc("qnap_exporter_last_refresh_timestamp_seconds",
+	"Time of the last successful refresh of the storage information.",
+	[]string{"host"}, nil)
+
+func newCachedCollector(host string, credentials manager.CredentialProvider, options *manager.ConfigOptions, logger *slog.Logger) *cachedCollector {
+	return &cachedCollector{
+		host:        host,
+		credentials: credentials,
+		options:     options,
+		logger:      logger.With("host", host),
+	}
+}
+
+// run refreshes the storage information at the interval, forever.
+func (c *cachedCollector) run(interval time.Duration) {
+	for {
+		c.refresh()
+
+		time.Sleep(interval)
+	}
+}
+
+func (c *cachedCollector) refresh() {
+	pools, luns, targets, err := c.retrieve()
+	if err != nil {
+		c.logger.Warn("Failed to refresh storage information", "error", err)
+
+		c.lock.Lock()
+		c.up = false
+		c.lock.Unlock()
+		return
+	}
+
+	c.lock.Lock()
+	c.up = true
+	c.lastRefresh = time.Now()
+	c.pools = pools
+	c.luns = luns
+	c.targets = targets
+	c.lock.Unlock()
+}
+
+func (c *cachedCollector) retrieve() (pools []*manager.StoragePool, luns []*manager.LUN, targets []*manager.ISCSITarget, err error) {
+	if c.session == nil {
+		c.session, err = manager.ConnectWithCredentials(c.host, c.credentials, c.options)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	if pools, err = c.session.GetStoragePools(); err != nil {
+		return nil, nil, nil, err
+	}
+	if luns, err = c.session.GetLUNs(); err != nil {
+		return nil, nil, nil, err
+	}
+	if targets, err = c.session.GetISCSITargets(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return pools, luns, targets, nil
+}
+
+// collect sends the cached metrics.
+func (c *cachedCollector) collect(ch chan<- prometheus.Metric) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	metrics.CollectUp(ch, c.host, c.up)
+
+	if !c.lastRefresh.IsZero() {
+		ch <- prometheus.MustNewConstMetric(lastRefreshDesc, prometheus.GaugeValue, float64(c.lastRefresh.Unix()), c.host)
+	}
+
+	// keep the last known values, while the NAS is unreachable
+	metrics.CollectStoragePools(ch, c.host, c.pools)
+	metrics.CollectLUNs(ch, c.host, c.luns)
+	metrics.CollectISCSITargets(ch, c.host, c.targets)
+}
+
+// exporter exports the cached metrics of all QNAP systems. The metrics of the systems
+// share the descriptors, so they are registered as one collector.
+type exporter []*cachedCollector
+
+var _ prometheus.Collector = exporter(nil)
+
+// Describe sends the descriptors of all metrics.
+func (e exporter) Describe(ch chan<- *prometheus.Desc) {
+	metrics.Describe(ch)
+
+	ch <- lastRefreshDesc
+}
+
+// Collect sends the cached metrics of all QNAP systems.
+func (e exporter) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range e {
+		c.collect(ch)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+	"github.com/nine-lives-later/go-qnap-disk-manager/qnapfake"
+)
+
+func newTestCollector(fake *qnapfake.Server) *cachedCollector {
+	return newCachedCollector(fake.URL, manager.StaticCredentials{Username: "admin", Password: "admin"},
+		&manager.ConfigOptions{APICallTimeout: 5 * time.Second},
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestExporter(t *testing.T) {
+	fake1 := qnapfake.NewServer("admin", "admin")
+	defer fake1.Close()
+	fake1.AddStoragePool(1, 100<<30)
+	fake2 := qnapfake.NewServer("admin", "admin")
+	defer fake2.Close()
+	fake2.AddStoragePool(1, 100<<30)
+
+	s, err := manager.Connect(fake1.URL, "admin", "admin", &manager.ConfigOptions{APICallTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer s.Close()
+	if _, err := s.CreateBlockBasedLUN(1, "data", 10, manager.LUNAllocateMode_Thin, false, 80); err != nil {
+		t.Fatalf("Failed to create LUN: %v", err)
+	}
+
+	e := exporter{newTestCollector(fake1), newTestCollector(fake2)}
+	for _, c := range e {
+		c.refresh()
+	}
+
+	// the collector is checked, so the metrics of all hosts have to match the descriptors
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(e); err != nil {
+		t.Fatalf("Failed to register exporter: %v", err)
+	}
+
+	expected := fmt.Sprintf(`
+# HELP qnap_lun_capacity_bytes Capacity of the LUN.
+# TYPE qnap_lun_capacity_bytes gauge
+qnap_lun_capacity_bytes{host="%[1]v",lun_index="1",lun_name="data",pool_id="1"} 1.073741824e+10
+# HELP qnap_up Whether the last retrieval of the storage information succeeded.
+# TYPE qnap_up gauge
+qnap_up{host="%[1]v"} 1
+qnap_up{host="%[2]v"} 1
+`, fake1.URL, fake2.URL)
+
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "qnap_lun_capacity_bytes", "qnap_up"); err != nil {
+		t.Fatalf("Unexpected metrics: %v", err)
+	}
+
+	// the last known values are kept, while the NAS is unreachable
+	fake1.Close()
+	e[0].refresh()
+
+	expected = strings.Replace(expected, fmt.Sprintf(`qnap_up{host="%v"} 1`, fake1.URL), fmt.Sprintf(`qnap_up{host="%v"} 0`, fake1.URL), 1)
+
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "qnap_lun_capacity_bytes", "qnap_up"); err != nil {
+		t.Fatalf("Unexpected metrics: %v", err)
+	}
+}
+
+func TestHostList_Duplicates(t *testing.T) {
+	var hosts hostList
+
+	for _, host := range []string{"storage1:443", "storage2:443", "storage1:443"} {
+		if err := hosts.Set(host); err != nil {
+			t.Fatalf("Failed to set host: %v", err)
+		}
+	}
+
+	if hosts.String() != "storage1:443,storage2:443" {
+		t.Fatalf("Duplicate hosts expected to be ignored: %v", hosts.String())
+	}
+}
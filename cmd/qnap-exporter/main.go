@@ -0,0 +1,119 @@
+// Command qnap-exporter exports the storage pools, LUNs and iSCSI targets
+// of one or more QNAP systems as Prometheus metrics.
+//
+// The storage information is refreshed in the background at a fixed interval,
+// so scrapes are served from the cache and never hit the NAS directly.
+//
+// Usage:
+//
+//	QNAP_USER=admin QNAP_PWD=secret qnap-exporter -host storage1:443 -host storage2:443
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+	"github.com/nine-lives-later/go-qnap-disk-manager/metrics"
+)
+
+type hostList []string
+
+func (h *hostList) String() string {
+	return strings.Join(*h, ",")
+}
+
+func (h *hostList) Set(value string) error {
+	// the metrics of a host must only be exported once
+	for _, host := range *h {
+		if host == value {
+			return nil
+		}
+	}
+
+	*h = append(*h, value)
+	return nil
+}
+
+func main() {
+	var hosts hostList
+
+	flag.Var(&hosts, "host", "QNAP system to export, can be repeated")
+	listen := flag.String("listen", ":9810", "address to serve the metrics on")
+	interval := flag.Duration("interval", 60*time.Second, "interval to refresh the storage information")
+	usernameFile := flag.String("username-file", "", "file containing the username, instead of the QNAP_USER environment variable")
+	passwordFile := flag.String("password-file", "", "file containing the password, instead of the QNAP_PWD environment variable")
+	fingerprint := flag.String("cert-fingerprint", "", "SHA-256 fingerprint of the NAS certificate to pin")
+	insecure := flag.Bool("insecure", false, "skip the verification of the NAS certificate")
+	debug := flag.Bool("debug", false, "enable debug logging")
+	flag.Parse()
+
+	level := slog.LevelInfo
+	if *debug {
+		level = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	if len(hosts) == 0 {
+		fmt.Fprintln(os.Stderr, "at least one -host is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// setup the credentials
+	var credentials manager.CredentialProvider = manager.EnvCredentials{}
+	if *usernameFile != "" || *passwordFile != "" {
+		credentials = manager.NewFileCredentials(*usernameFile, *passwordFile)
+	}
+
+	// setup the metrics
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+	recorder, err := metrics.NewRecorder(registry)
+	if err != nil {
+		logger.Error("Failed to setup metrics", "error", err)
+		os.Exit(1)
+	}
+
+	retryPolicy := manager.DefaultRetryPolicy
+
+	options := &manager.ConfigOptions{
+		APICallTimeout:              60 * time.Second,
+		IgnoreInvalidSSLCertificate: *insecure,
+		CertificateFingerprint:      *fingerprint,
+		Logger:                      logger,
+		MetricsRecorder:             recorder,
+		RetryPolicy:                 &retryPolicy,
+	}
+
+	var e exporter
+
+	for _, host := range hosts {
+		c := newCachedCollector(host, credentials, options, logger)
+		e = append(e, c)
+
+		go c.run(*interval)
+	}
+
+	registry.MustRegister(e)
+
+	// serve the metrics
+	http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	logger.Info("Serving metrics", "address", *listen, "hosts", hosts.String())
+
+	if err := http.ListenAndServe(*listen, nil); err != nil {
+		logger.Error("Failed to serve metrics", "error", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+	"github.com/nine-lives-later/go-qnap-disk-manager/declarative"
+)
+
+// command validates the arguments and returns the action to run, before connecting to the NAS.
+type command func(args []string) (action, error)
+
+// action runs the command against the NAS.
+type action func(ctx context.Context, s *manager.QnapSession, p printer) error
+
+var commands = map[string]command{
+	"pools list":   listPools,
+	"luns list":    listLUNs,
+	"luns get":     getLUN,
+	"luns create":  createLUN,
+	"luns delete":  deleteLUN,
+	"luns assign":  assignLUN,
+	"targets list": listTargets,
+	"plan":         planManifest,
+	"apply":        applyManifest,
+}
+
+func listPools(args []string) (action, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("%w: pools list takes no arguments", errUsage)
+	}
+
+	return func(ctx context.Context, s *manager.QnapSession, p printer) error {
+		pools, err := s.GetStoragePoolsContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		return p.print(pools, []string{"POOL", "STATUS", "CAPACITY", "ALLOCATED", "FREE"}, func(row func(...interface{})) {
+			for _, pool := range pools {
+				row(pool.PoolID, pool.PoolStatus, formatBytes(pool.CapacityBytes), formatBytes(pool.AllocatedBytes), formatBytes(pool.FreesizeBytes))
+			}
+		})
+	}, nil
+}
+
+func listLUNs(args []string) (action, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("%w: luns list takes no arguments", errUsage)
+	}
+
+	return func(ctx context.Context, s *manager.QnapSession, p printer) error {
+		luns, err := s.GetLUNsContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		return p.print(luns, lunColumns, func(row func(...interface{})) {
+			for _, lun := range luns {
+				row(lunRow(lun)...)
+			}
+		})
+	}, nil
+}
+
+func getLUN(args []string) (action, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%w: luns get requires the LUN index", errUsage)
+	}
+
+	lunIndex, err := parseIndex("LUN index", args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, s *manager.QnapSession, p printer) error {
+		lun, err := requireLUN(ctx, s, lunIndex)
+		if err != nil {
+			return err
+		}
+
+		return p.print(lun, lunColumns, func(row func(...interface{})) {
+			row(lunRow(lun)...)
+		})
+	}, nil
+}
+
+func createLUN(args []string) (action, error) {
+	flags := flag.NewFlagSet("luns create", flag.ContinueOnError)
+	poolID := flags.Int("pool", 1, "storage pool to create the LUN in")
+	name := flags.String("name", "", "name of the LUN")
+	sizeGB := flags.Int("size", 0, "capacity of the LUN in GB")
+	thick := flags.Bool("thick", false, "allocate the whole capacity upfront")
+	ssdCache := flags.Bool("ssd-cache", false, "use the SSD cache")
+	threshold := flags.Int("threshold", 80, "alert threshold in percent")
+	wait := flags.Bool("wait", false, "wait for the LUN volume to become ready")
+	targetIndex := flags.Int("target", -1, "iSCSI target to assign the LUN to")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if flags.NArg() != 0 || *name == "" || *sizeGB <= 0 {
+		return nil, fmt.Errorf("%w: luns create requires -name and -size", errUsage)
+	}
+
+	allocateMode := manager.LUNAllocateMode_Thin
+	if *thick {
+		allocateMode = manager.LUNAllocateMode_Thick
+	}
+
+	return func(ctx context.Context, s *manager.QnapSession, p printer) error {
+		lun, err := s.CreateBlockBasedLUNContext(ctx, *poolID, *name, *sizeGB, allocateMode, *ssdCache, *threshold, nil)
+		if err != nil {
+			return err
+		}
+
+		if *wait {
+			if lun, err = s.WaitForLUNVolumeContext(ctx, lun.LUNIndex); err != nil {
+				return err
+			}
+		}
+
+		if *targetIndex >= 0 {
+			if err := s.AssignLUNContext(ctx, lun.LUNIndex, *targetIndex); err != nil {
+				return err
+			}
+			if lun, err = s.GetLUNByIndexContext(ctx, lun.LUNIndex); err != nil {
+				return err
+			}
+			if lun == nil {
+				return fmt.Errorf("%w: LUN vanished after assignment", errNotFound)
+			}
+		}
+
+		return p.print(lun, lunColumns, func(row func(...interface{})) {
+			row(lunRow(lun)...)
+		})
+	}, nil
+}
+
+func deleteLUN(args []string) (action, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%w: luns delete requires the LUN index", errUsage)
+	}
+
+	lunIndex, err := parseIndex("LUN index", args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, s *manager.QnapSession, p printer) error {
+		// report unknown LUNs as not found, instead of a generic API error
+		if _, err := requireLUN(ctx, s, lunIndex); err != nil {
+			return err
+		}
+
+		return s.DeleteLUNContext(ctx, lunIndex)
+	}, nil
+}
+
+// requireLUN returns the LUN with the index, errNotFound if there is none.
+func requireLUN(ctx context.Context, s *manager.QnapSession, lunIndex int) (*manager.LUN, error) {
+	lun, err := s.GetLUNByIndexContext(ctx, lunIndex)
+	if err != nil {
+		return nil, err
+	}
+	if lun == nil {
+		return nil, fmt.Errorf("%w: LUN %v", errNotFound, lunIndex)
+	}
+
+	return lun, nil
+}
+
+func assignLUN(args []string) (action, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("%w: luns assign requires the LUN index and the target index", errUsage)
+	}
+
+	lunIndex, err := parseIndex("LUN index", args[0])
+	if err != nil {
+		return nil, err
+	}
+	targetIndex, err := parseIndex("target index", args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, s *manager.QnapSession, p printer) error {
+		return s.AssignLUNContext(ctx, lunIndex, targetIndex)
+	}, nil
+}
+
+func listTargets(args []string) (action, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("%w: targets list takes no arguments", errUsage)
+	}
+
+	return func(ctx context.Context, s *manager.QnapSession, p printer) error {
+		targets, err := s.GetISCSITargetsContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		return p.print(targets, []string{"TARGET", "NAME", "IQN", "STATUS"}, func(row func(...interface{})) {
+			for _, target := range targets {
+				row(target.TargetIndex, target.TargetName, target.TargetIQN, target.TargetStatus)
+			}
+		})
+	}, nil
+}
+
+var lunColumns = []string{"LUN", "NAME", "POOL", "CAPACITY", "THIN", "STATUS", "TARGET", "NAA"}
+
+func lunRow(lun *manager.LUN) []interface{} {
+	target := "-"
+	if lun.LUNTargetList.SingleRow != nil {
+		target = strconv.Itoa(lun.LUNTargetList.SingleRow.TargetIndex)
+	}
+
+	return []interface{}{lun.LUNIndex, lun.LUNName, lun.PoolID, formatBytes(lun.CapacityBytes), lun.LUNThinAllocate, lun.LUNStatus, target, lun.LUNNAA}
+}
+
+func parseIndex(what, value string) (int, error) {
+	index, err := strconv.Atoi(value)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("%w: invalid %v: %v", errUsage, what, value)
+	}
+	return index, nil
+}
+
+func planManifest(args []string) (action, error) {
+	manifest, options, err := parseManifestArgs("plan", args)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, s *manager.QnapSession, p printer) error {
+		plan, err := declarative.NewPlan(ctx, s, manifest, options)
+		if err != nil {
+			return err
+		}
+
+		return printPlan(p, plan)
+	}, nil
+}
+
+func applyManifest(args []string) (action, error) {
+	manifest, options, err := parseManifestArgs("apply", args)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, s *manager.QnapSession, p printer) error {
+		plan, err := declarative.NewPlan(ctx, s, manifest, options)
+		if err != nil {
+			return err
+		}
+
+		if err := printPlan(p, plan); err != nil {
+			return err
+		}
+
+		return declarative.Apply(ctx, s, plan, func(action *declarative.Action) {
+			fmt.Fprintf(os.Stderr, "Applied: %v\n", action)
+		})
+	}, nil
+}
+
+// parseManifestArgs reads the manifest file of the plan and apply commands.
+func parseManifestArgs(name string, args []string) (*declarative.Manifest, *declarative.PlanOptions, error) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	file := flags.String("f", "", "manifest file in YAML or JSON format")
+	prune := flags.Bool("prune", false, "delete LUNs which are not part of the manifest")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if flags.NArg() != 0 || *file == "" {
+		return nil, nil, fmt.Errorf("%w: %v requires -f", errUsage, name)
+	}
+
+	manifest, err := declarative.ReadManifest(*file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return manifest, &declarative.PlanOptions{Prune: *prune}, nil
+}
+
+func printPlan(p printer, plan *declarative.Plan) error {
+	if p.format != "table" {
+		return p.print(plan, nil, nil)
+	}
+
+	return plan.Print(p.out)
+}
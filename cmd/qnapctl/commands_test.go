@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+	"github.com/nine-lives-later/go-qnap-disk-manager/qnapfake"
+)
+
+// runCommand dispatches the command against the fake and returns the output.
+func runCommand(t *testing.T, fake *qnapfake.Server, format string, args ...string) (string, error) {
+	t.Helper()
+
+	var out bytes.Buffer
+
+	p, err := newPrinter(format, &out)
+	if err != nil {
+		t.Fatalf("Failed to create printer: %v", err)
+	}
+
+	t.Setenv("QNAP_PWD", "admin")
+
+	err = dispatch(context.Background(), &globalFlags{host: fake.URL, username: "admin"}, p, args)
+	return out.String(), err
+}
+
+func TestCommands(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	s, err := manager.Connect(fake.URL, "admin", "admin", &manager.ConfigOptions{APICallTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer s.Close()
+	if _, err := s.CreateISCSITarget("k8s", "k8s"); err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+
+	out, err := runCommand(t, fake, "table", "luns", "create", "-pool", "1", "-name", "data01", "-size", "10", "-thick")
+	if err != nil {
+		t.Fatalf("Failed to create LUN: %v", err)
+	}
+	if !strings.Contains(out, "data01") || !strings.Contains(out, "10.0 GiB") {
+		t.Fatalf("Created LUN expected in output: %v", out)
+	}
+
+	if _, err := runCommand(t, fake, "table", "luns", "assign", "1", "0"); err != nil {
+		t.Fatalf("Failed to assign LUN: %v", err)
+	}
+
+	out, err = runCommand(t, fake, "json", "luns", "list")
+	if err != nil {
+		t.Fatalf("Failed to list LUNs: %v", err)
+	}
+
+	var luns []*manager.LUN
+	if err := json.Unmarshal([]byte(out), &luns); err != nil {
+		t.Fatalf("Failed to parse LUNs: %v", err)
+	}
+	if len(luns) != 1 || luns[0].LUNName != "data01" || luns[0].LUNThinAllocate || luns[0].LUNTargetList.SingleRow == nil {
+		t.Fatalf("Wrong LUNs: %v", out)
+	}
+
+	for _, args := range [][]string{{"luns", "get", "1"}, {"pools", "list"}, {"targets", "list"}} {
+		if out, err := runCommand(t, fake, "table", args...); err != nil || len(strings.Split(strings.TrimSpace(out), "\n")) != 2 {
+			t.Fatalf("Header and one row expected for %v: %v, %v", args, out, err)
+		}
+	}
+
+	if _, err := runCommand(t, fake, "table", "luns", "delete", "1"); err != nil {
+		t.Fatalf("Failed to delete LUN: %v", err)
+	}
+	if len(fake.LUNs()) != 0 {
+		t.Fatalf("LUN not deleted: %v", fake.LUNs())
+	}
+
+	if _, err := runCommand(t, fake, "table", "luns", "delete", "1"); !errors.Is(err, errNotFound) {
+		t.Fatalf("Not found expected for deleted LUN: %v", err)
+	}
+}
+
+func TestCommands_InvalidUsage(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	var lock sync.Mutex
+	logins := 0
+
+	fake.SetInterceptor(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/cgi-bin/authLogin.cgi" {
+			lock.Lock()
+			logins++
+			lock.Unlock()
+		}
+		return false
+	})
+
+	for _, args := range [][]string{
+		{"pools"},
+		{"pools", "list", "1"},
+		{"luns", "get"},
+		{"luns", "get", "x"},
+		{"luns", "delete", "-1"},
+		{"luns", "assign", "1"},
+		{"luns", "create", "-name", "data01"},
+		{"luns", "create", "-size", "10"},
+		{"luns", "create", "-unknown"},
+		{"plan"},
+	} {
+		if _, err := runCommand(t, fake, "table", args...); !errors.Is(err, errUsage) {
+			t.Errorf("Usage error expected for %v: %v", args, err)
+		}
+	}
+
+	// the arguments are validated before connecting
+	if logins != 0 {
+		t.Fatalf("No login expected for invalid arguments, got %v logins", logins)
+	}
+}
+
+func TestCommands_Plan(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	file := filepath.Join(t.TempDir(), "storage.yaml")
+	if err := os.WriteFile(file, []byte("luns:\n  - name: data01\n    pool: 1\n    size_gb: 10\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	out, err := runCommand(t, fake, "table", "plan", "-f", file)
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	if !strings.Contains(out, "+ create LUN data01") || !strings.Contains(out, "Plan: 1 to create, 0 to update, 0 to delete.") {
+		t.Fatalf("Unexpected plan: %v", out)
+	}
+	if len(fake.LUNs()) != 0 {
+		t.Fatal("Plan must not change anything")
+	}
+}
+
+func TestParseIndex(t *testing.T) {
+	if index, err := parseIndex("LUN index", "5"); err != nil || index != 5 {
+		t.Fatalf("Expected 5, got %v, %v", index, err)
+	}
+
+	for _, value := range []string{"", "x", "-1", "1.5"} {
+		if _, err := parseIndex("LUN index", value); !errors.Is(err, errUsage) {
+			t.Errorf("Usage error expected for '%v': %v", value, err)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Command qnapctl manages the storage pools, LUNs and iSCSI targets of a QNAP system.
+//
+// Usage:
+//
+//	qnapctl [flags] pools list
+//	qnapctl [flags] luns list
+//	qnapctl [flags] luns get <lun-index>
+//	qnapctl [flags] luns create -pool <id> -name <name> -size <GB> [-thick] [-ssd-cache] [-threshold <percent>] [-target <index>]
+//	qnapctl [flags] luns delete <lun-index>
+//	qnapctl [flags] luns assign <lun-index> <target-index>
+//	qnapctl [flags] targets list
+//	qnapctl [flags] plan -f <manifest> [-prune]
+//	qnapctl [flags] apply -f <manifest> [-prune]
+//
+// The host and credentials are read from the flags or the QNAP_HOSTNAME, QNAP_USER
+// and QNAP_PWD environment variables, or from the profile selected by -profile.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+)
+
+// exit codes
+const (
+	exitOK               = 0
+	exitError            = 1
+	exitUsage            = 2
+	exitAuthFailed       = 3
+	exitPermissionDenied = 4
+	exitNotFound         = 5
+	exitTimeout          = 6
+)
+
+var (
+	errUsage    = errors.New("invalid usage")
+	errNotFound = errors.New("not found")
+)
+
+type globalFlags struct {
+	profile      string
+	host         string
+	username     string
+	passwordFile string
+	fingerprint  string
+	insecure     bool
+	output       string
+	timeout      time.Duration
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	var globals globalFlags
+
+	flags := flag.NewFlagSet("qnapctl", flag.ContinueOnError)
+	flags.StringVar(&globals.profile, "profile", os.Getenv("QNAP_PROFILE"), "profile of the profiles file to connect with, instead of the host and credential flags (QNAP_PROFILE)")
+	flags.StringVar(&globals.host, "host", os.Getenv("QNAP_HOSTNAME"), "QNAP system to connect to (QNAP_HOSTNAME)")
+	flags.StringVar(&globals.username, "user", os.Getenv("QNAP_USER"), "username to login with (QNAP_USER)")
+	flags.StringVar(&globals.passwordFile, "password-file", "", "file containing the password, instead of the QNAP_PWD environment variable")
+	flags.StringVar(&globals.fingerprint, "cert-fingerprint", os.Getenv("QNAP_CERT_FINGERPRINT"), "SHA-256 fingerprint of the NAS certificate to pin (QNAP_CERT_FINGERPRINT)")
+	flags.BoolVar(&globals.insecure, "insecure", false, "skip the verification of the NAS certificate")
+	flags.StringVar(&globals.output, "output", "table", "output format: table, json or yaml")
+	flags.DurationVar(&globals.timeout, "timeout", 5*time.Minute, "timeout of the whole command")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: qnapctl [flags] <pools|luns|targets> <action> [arguments]")
+		fmt.Fprintln(flags.Output(), "       qnapctl [flags] <plan|apply> -f <manifest> [-prune]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() < 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	printer, err := newPrinter(globals.output, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), globals.timeout)
+	defer cancel()
+
+	err = dispatch(ctx, &globals, printer, flags.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+
+	return exitCode(err)
+}
+
+func dispatch(ctx context.Context, globals *globalFlags, printer printer, args []string) error {
+	// commands consist of one or two words, e.g. "apply" or "luns list"
+	words := 1
+	cmd, ok := commands[args[0]]
+	if !ok && len(args) >= 2 {
+		words = 2
+		cmd, ok = commands[args[0]+" "+args[1]]
+	}
+	if !ok {
+		return fmt.Errorf("%w: unknown command: %v", errUsage, strings.Join(args, " "))
+	}
+
+	// validate the arguments before connecting to the NAS
+	action, err := cmd(args[words:])
+	if err != nil {
+		return err
+	}
+
+	session, err := connect(globals)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	return action(ctx, session, printer)
+}
+
+func connect(globals *globalFlags) (*manager.QnapSession, error) {
+	if globals.profile != "" {
+		return manager.LoadProfile(globals.profile)
+	}
+
+	if globals.host == "" {
+		return nil, fmt.Errorf("%w: no host defined, use -host or QNAP_HOSTNAME", errUsage)
+	}
+
+	credentials := manager.CredentialProvider(manager.EnvCredentials{})
+
+	if globals.passwordFile != "" || globals.username != "" {
+		credentials = &cliCredentials{username: globals.username, passwordFile: globals.passwordFile}
+	}
+
+	return manager.ConnectWithCredentials(globals.host, credentials, &manager.ConfigOptions{
+		APICallTimeout:              60 * time.Second,
+		IgnoreInvalidSSLCertificate: globals.insecure,
+		CertificateFingerprint:      globals.fingerprint,
+	})
+}
+
+// cliCredentials combines the username flag with the password from file or environment.
+type cliCredentials struct {
+	username     string
+	passwordFile string
+}
+
+func (c *cliCredentials) Credentials() (string, string, error) {
+	if c.username == "" {
+		return "", "", fmt.Errorf("%w: no username defined, use -user or QNAP_USER", errUsage)
+	}
+
+	if c.passwordFile != "" {
+		password, err := os.ReadFile(c.passwordFile)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read password file: %w", err)
+		}
+		return c.username, string(trimNewline(password)), nil
+	}
+
+	password := os.Getenv("QNAP_PWD")
+	if password == "" {
+		return "", "", fmt.Errorf("%w: no password defined, use -password-file or QNAP_PWD", errUsage)
+	}
+
+	return c.username, password, nil
+}
+
+func trimNewline(b []byte) []byte {
+	for len(b) > 0 && (b[len(b)-1] == '\n' || b[len(b)-1] == '\r') {
+		b = b[:len(b)-1]
+	}
+	return b
+}
+
+// exitCode maps the error to the exit code of the command.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage), errors.Is(err, manager.ErrProfileNotFound):
+		return exitUsage
+	case errors.Is(err, manager.ErrAuthenticationFailed), errors.Is(err, manager.ErrSecurityCodeRequired), errors.Is(err, manager.ErrSecurityCodeInvalid):
+		return exitAuthFailed
+	case errors.Is(err, manager.ErrPermissionDenied):
+		return exitPermissionDenied
+	case errors.Is(err, errNotFound):
+		return exitNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		return exitTimeout
+	default:
+		return exitError
+	}
+}
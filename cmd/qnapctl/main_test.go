@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+	"github.com/nine-lives-later/go-qnap-disk-manager/qnapfake"
+)
+
+func TestExitCode(t *testing.T) {
+	for _, tc := range []struct {
+		err      error
+		expected int
+	}{
+		{nil, exitOK},
+		{errors.New("failed"), exitError},
+		{fmt.Errorf("%w: unknown command", errUsage), exitUsage},
+		{fmt.Errorf("failed to load profile: %w", manager.ErrProfileNotFound), exitUsage},
+		{fmt.Errorf("failed to perform request: %w", manager.ErrAuthenticationFailed), exitAuthFailed},
+		{fmt.Errorf("failed to perform request: %w", manager.ErrSecurityCodeRequired), exitAuthFailed},
+		{fmt.Errorf("failed to perform request: %w", manager.ErrSecurityCodeInvalid), exitAuthFailed},
+		{fmt.Errorf("failed to delete LUN: %w", manager.ErrPermissionDenied), exitPermissionDenied},
+		{fmt.Errorf("%w: LUN 5", errNotFound), exitNotFound},
+		{fmt.Errorf("failed to perform request: %w", context.DeadlineExceeded), exitTimeout},
+	} {
+		if code := exitCode(tc.err); code != tc.expected {
+			t.Errorf("Expected exit code %v for '%v', got %v", tc.expected, tc.err, code)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	t.Setenv("QNAP_HOSTNAME", "")
+	t.Setenv("QNAP_PROFILE", "")
+	t.Setenv("QNAP_PWD", "admin")
+
+	for _, tc := range []struct {
+		args     []string
+		expected int
+	}{
+		{[]string{}, exitUsage},
+		{[]string{"pools", "list"}, exitUsage}, // no host
+		{[]string{"-output", "xml", "pools", "list"}, exitUsage},
+		{[]string{"-host", fake.URL, "-user", "admin", "luns", "rename"}, exitUsage},
+		{[]string{"-host", fake.URL, "-user", "other", "pools", "list"}, exitAuthFailed},
+		{[]string{"-host", fake.URL, "-user", "admin", "luns", "delete", "42"}, exitNotFound},
+		{[]string{"-host", fake.URL, "-user", "admin", "luns", "get", "42"}, exitNotFound},
+		{[]string{"-host", fake.URL, "-user", "admin", "-output", "json", "pools", "list"}, exitOK},
+	} {
+		if code := run(tc.args); code != tc.expected {
+			t.Errorf("Expected exit code %v for %v, got %v", tc.expected, tc.args, code)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// printer writes the result of a command in the selected output format.
+type printer struct {
+	format string
+	out    io.Writer
+}
+
+func newPrinter(format string, out io.Writer) (printer, error) {
+	switch format {
+	case "table", "json", "yaml":
+		return printer{format: format, out: out}, nil
+	default:
+		return printer{}, fmt.Errorf("unknown output format: %v (table, json or yaml)", format)
+	}
+}
+
+// print writes the value as JSON or YAML, or the rows produced by the function as table.
+func (p printer) print(value interface{}, columns []string, rows func(row func(...interface{}))) error {
+	switch p.format {
+	case "json":
+		enc := json.NewEncoder(p.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(value)
+
+	case "yaml":
+		enc := yaml.NewEncoder(p.out)
+		enc.SetIndent(2)
+		if err := enc.Encode(value); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(columns, "\t"))
+
+	rows(func(values ...interface{}) {
+		cells := make([]string, len(values))
+		for i, v := range values {
+			cells[i] = fmt.Sprint(v)
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	})
+
+	return w.Flush()
+}
+
+// formatBytes formats the size in binary units, e.g. 10.0 GiB.
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
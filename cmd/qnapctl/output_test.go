@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFormatBytes(t *testing.T) {
+	for size, expected := range map[int64]string{
+		0:             "0 B",
+		1023:          "1023 B",
+		1024:          "1.0 KiB",
+		1536:          "1.5 KiB",
+		10 << 30:      "10.0 GiB",
+		3 << 40:       "3.0 TiB",
+		1<<50 + 1<<49: "1.5 PiB",
+	} {
+		if s := formatBytes(size); s != expected {
+			t.Errorf("Expected %v for %v bytes, got %v", expected, size, s)
+		}
+	}
+}
+
+func TestPrinter(t *testing.T) {
+	value := []struct {
+		Name string `json:"name" yaml:"name"`
+		Size int    `json:"size" yaml:"size"`
+	}{{"data01", 10}, {"logs", 200}}
+
+	rows := func(row func(...interface{})) {
+		for _, v := range value {
+			row(v.Name, v.Size)
+		}
+	}
+
+	for format, expected := range map[string]string{
+		"table": "NAME    SIZE\ndata01  10\nlogs    200\n",
+		"json":  "[\n  {\n    \"name\": \"data01\",\n    \"size\": 10\n  },\n  {\n    \"name\": \"logs\",\n    \"size\": 200\n  }\n]\n",
+		"yaml":  "- name: data01\n  size: 10\n- name: logs\n  size: 200\n",
+	} {
+		var out bytes.Buffer
+
+		p, err := newPrinter(format, &out)
+		if err != nil {
+			t.Fatalf("Failed to create %v printer: %v", format, err)
+		}
+		if err := p.print(value, []string{"NAME", "SIZE"}, rows); err != nil {
+			t.Fatalf("Failed to print %v: %v", format, err)
+		}
+		if out.String() != expected {
+			t.Errorf("Unexpected %v output:\n%v", format, out.String())
+		}
+	}
+
+	if _, err := newPrinter("xml", &bytes.Buffer{}); err == nil {
+		t.Fatal("Error expected for unknown format")
+	}
+}
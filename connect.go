@@ -1,30 +1,82 @@
 package manager
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
 var defaultConfigOptions = ConfigOptions{
-	APICallTimeout:              60 * time.Second,
-	IgnoreInvalidSSLCertificate: true,
+	APICallTimeout: 60 * time.Second,
 }
 
 // ConfigOptions contains some advanced settings on server communication.
 type ConfigOptions struct {
 	APICallTimeout              time.Duration
-	IgnoreInvalidSSLCertificate bool
+	IgnoreInvalidSSLCertificate bool                 // disables the certificate verification, prefer CertificateFingerprint for self-signed certificates
+	CACertificateFile           string               // PEM file with CA certificates to trust, instead of the system pool
+	CACertificatesPEM           []byte               // PEM encoded CA certificates to trust, instead of the system pool
+	CertificateFingerprint      string               // SHA-256 fingerprint (hex) of the NAS certificate to pin, e.g. a self-signed one
+	ClientCertificates          []tls.Certificate    // client certificates to present to the NAS
+	MinTLSVersion               uint16               // minimum TLS version, defaults to TLS 1.2
+	TLSServerName               string               // overrides the server name (SNI) used for the TLS handshake and verification
+	RememberMe                  bool                 // request a long-lived login token, see QnapSession.Token()
+	SecurityCodeProvider        SecurityCodeProvider // provides the 2-step verification code, if enabled for the account
+	HTTPClient                  *http.Client         // custom HTTP client to use, e.g. with a shared connection pool; it is copied, the TLS options are not applied
+	Transport                   http.RoundTripper    // custom HTTP transport to use, e.g. for tracing; the TLS options are not applied
+	ProxyURL                    string               // HTTP proxy to use, cannot be combined with HTTPClient or Transport
+	Logger                      *slog.Logger         // receives debug logs of every API call, credentials are always redacted
+	MetricsRecorder             MetricsRecorder      // receives the metrics of every API call
+	TracerProvider              trace.TracerProvider // creates the OpenTelemetry spans of every call, defaults to the global provider
+	RetryPolicy                 *RetryPolicy         // retry transient errors, nil to disable retries
+	Cache                       *CacheOptions        // cache the listing calls, nil to disable caching
+	Capabilities                *Capabilities        // overrides the API parameters detected from the firmware version
+	RequestMiddlewares          []RequestMiddleware  // called before every API request
+	ResponseMiddlewares         []ResponseMiddleware // called after every API response
 }
 
 // QnapSession is a container for our session state.
+// It is safe for concurrent use by multiple goroutines. Mutating calls
+// (like CreateBlockBasedLUN, DeleteLUN or AssignLUN) of the same session
+// or session pool are serialized, while reading calls run in parallel.
 type QnapSession struct {
-	host      string
-	sessionID string
-	conn      *resty.Client
-	options   *ConfigOptions
+	lock      sync.RWMutex // guards the session state below
+	loginLock sync.Mutex   // serializes login and logout
+
+	host         string
+	sessionID    string
+	token        string
+	username     string
+	nasHostname  string
+	isAdmin      bool
+	credentials  CredentialProvider
+	conn         *resty.Client
+	options      *ConfigOptions
+	systemInfo   *SystemInfo
+	capabilities Capabilities
+	cache        *responseCache
+	shared       *sessionShared
+}
+
+// sessionShared is the state shared by all sessions of a session pool.
+type sessionShared struct {
+	mutationLock sync.Mutex
+	cache        *responseCache
+}
+
+func newSessionShared(configOptions *ConfigOptions) *sessionShared {
+	shared := &sessionShared{}
+	if configOptions != nil {
+		shared.cache = newResponseCache(configOptions.Cache)
+	}
+	return shared
 }
 
 // String returns the session's hostname.
@@ -32,8 +84,50 @@ func (s *QnapSession) String() string {
 	return s.host
 }
 
+// SessionID returns the ID of the current session, empty if not logged-in.
+func (s *QnapSession) SessionID() string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.sessionID
+}
+
+// Username returns the name of the logged-in user.
+func (s *QnapSession) Username() string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.username
+}
+
+// NASHostname returns the hostname the QNAP system reported on login.
+func (s *QnapSession) NASHostname() string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.nasHostname
+}
+
+// IsAdmin returns whether the logged-in user has administrator privileges.
+func (s *QnapSession) IsAdmin() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.isAdmin
+}
+
 // Connect sets up our connection to the QNAP system.
 func Connect(host, username, password string, configOptions *ConfigOptions) (*QnapSession, error) {
+	return ConnectWithCredentials(host, StaticCredentials{Username: username, Password: password}, configOptions)
+}
+
+// ConnectWithCredentials sets up our connection to the QNAP system.
+// The credential provider is used on login and on every automatic re-login after the session expired.
+func ConnectWithCredentials(host string, credentials CredentialProvider, configOptions *ConfigOptions) (*QnapSession, error) {
+	return connect(host, credentials, configOptions, newSessionShared(configOptions))
+}
+
+func connect(host string, credentials CredentialProvider, configOptions *ConfigOptions, shared *sessionShared) (*QnapSession, error) {
 	if !strings.HasPrefix(host, "http") {
 		host = fmt.Sprintf("https://%s", host)
 	}
@@ -42,19 +136,23 @@ func Connect(host, username, password string, configOptions *ConfigOptions) (*Qn
 	}
 
 	// create the session
-	session := &QnapSession{
-		host:    host,
-		conn:    resty.New().SetHostURL(host).SetTimeout(configOptions.APICallTimeout),
-		options: configOptions,
+	conn, err := newRestyClient(host, configOptions)
+	if err != nil {
+		return nil, err
 	}
 
-	// setup SSL certificate handling
-	if configOptions.IgnoreInvalidSSLCertificate {
-		session.conn.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	session := &QnapSession{
+		host:         host,
+		credentials:  credentials,
+		conn:         conn,
+		options:      configOptions,
+		capabilities: configOptions.capabilities(""),
+		cache:        shared.cache,
+		shared:       shared,
 	}
 
 	// perform login
-	err := session.Login(username, password)
+	err = session.loginWithProvider(context.Background())
 	if err != nil {
 		return nil, err
 	}
@@ -63,6 +161,14 @@ func Connect(host, username, password string, configOptions *ConfigOptions) (*Qn
 	return session, nil
 }
 
+// capabilities returns the overridden capabilities, or the ones of the firmware version.
+func (o *ConfigOptions) capabilities(firmwareVersion string) Capabilities {
+	if o != nil && o.Capabilities != nil {
+		return *o.Capabilities
+	}
+	return lookupCapabilities(firmwareVersion)
+}
+
 func (s *QnapSession) Close() error {
 	return s.Logout()
 }
@@ -0,0 +1,122 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CredentialProvider provides the username and password to login with.
+// It is called on Connect and on every automatic re-login, so rotated passwords are picked up.
+type CredentialProvider interface {
+	Credentials() (username, password string, err error)
+}
+
+// StaticCredentials provides fixed credentials.
+type StaticCredentials struct {
+	Username string
+	Password string
+}
+
+// Credentials returns the fixed credentials.
+func (c StaticCredentials) Credentials() (string, string, error) {
+	return c.Username, c.Password, nil
+}
+
+// EnvCredentials reads the credentials from environment variables.
+type EnvCredentials struct {
+	UsernameVar string // defaults to QNAP_USER
+	PasswordVar string // defaults to QNAP_PWD
+}
+
+// Credentials reads the credentials from the environment variables.
+func (c EnvCredentials) Credentials() (string, string, error) {
+	usernameVar := c.UsernameVar
+	if usernameVar == "" {
+		usernameVar = "QNAP_USER"
+	}
+	passwordVar := c.PasswordVar
+	if passwordVar == "" {
+		passwordVar = "QNAP_PWD"
+	}
+
+	username := os.Getenv(usernameVar)
+	if username == "" {
+		return "", "", fmt.Errorf("environment variable %v is not set", usernameVar)
+	}
+	password := os.Getenv(passwordVar)
+	if password == "" {
+		return "", "", fmt.Errorf("environment variable %v is not set", passwordVar)
+	}
+
+	return username, password, nil
+}
+
+// FileCredentials reads the credentials from files, e.g. a mounted Kubernetes secret.
+// The files are read again when they have been changed.
+type FileCredentials struct {
+	UsernameFile string
+	PasswordFile string
+
+	lock     sync.Mutex
+	modTime  time.Time
+	username string
+	password string
+}
+
+// NewFileCredentials creates a new provider reading the credentials from the files.
+func NewFileCredentials(usernameFile, passwordFile string) *FileCredentials {
+	return &FileCredentials{
+		UsernameFile: usernameFile,
+		PasswordFile: passwordFile,
+	}
+}
+
+// Credentials returns the content of the files, trimmed of surrounding whitespace.
+func (c *FileCredentials) Credentials() (string, string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// check for changes
+	modTime, err := latestModTime(c.UsernameFile, c.PasswordFile)
+	if err != nil {
+		return "", "", err
+	}
+	if modTime.Equal(c.modTime) && c.username != "" {
+		return c.username, c.password, nil
+	}
+
+	// read the files
+	username, err := os.ReadFile(c.UsernameFile)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read username file: %w", err)
+	}
+	password, err := os.ReadFile(c.PasswordFile)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read password file: %w", err)
+	}
+
+	c.username = strings.TrimSpace(string(username))
+	c.password = strings.TrimSpace(string(password))
+	c.modTime = modTime
+
+	return c.username, c.password, nil
+}
+
+func latestModTime(files ...string) (time.Time, error) {
+	var latest time.Time
+
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to check credential file: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
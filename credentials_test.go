@@ -0,0 +1,61 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEnvCredentials(t *testing.T) {
+	os.Setenv("QNAP_UNITTEST_USER", "admin")
+	os.Setenv("QNAP_UNITTEST_PWD", "s3cr3t")
+	defer os.Unsetenv("QNAP_UNITTEST_USER")
+	defer os.Unsetenv("QNAP_UNITTEST_PWD")
+
+	username, password, err := EnvCredentials{UsernameVar: "QNAP_UNITTEST_USER", PasswordVar: "QNAP_UNITTEST_PWD"}.Credentials()
+	if err != nil {
+		t.Fatalf("Failed to read credentials: %v", err)
+	}
+	if username != "admin" || password != "s3cr3t" {
+		t.Fatalf("Wrong credentials: %v / %v", username, password)
+	}
+
+	_, _, err = EnvCredentials{UsernameVar: "QNAP_UNITTEST_MISSING"}.Credentials()
+	if err == nil {
+		t.Fatal("Error expected")
+	}
+}
+
+func TestFileCredentials_Rotation(t *testing.T) {
+	dir := t.TempDir()
+
+	usernameFile := filepath.Join(dir, "username")
+	passwordFile := filepath.Join(dir, "password")
+
+	os.WriteFile(usernameFile, []byte("admin\n"), 0600)
+	os.WriteFile(passwordFile, []byte("first\n"), 0600)
+
+	credentials := NewFileCredentials(usernameFile, passwordFile)
+
+	username, password, err := credentials.Credentials()
+	if err != nil {
+		t.Fatalf("Failed to read credentials: %v", err)
+	}
+	if username != "admin" || password != "first" {
+		t.Fatalf("Wrong credentials: %v / %v", username, password)
+	}
+
+	// rotate the password
+	os.WriteFile(passwordFile, []byte("second\n"), 0600)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(passwordFile, later, later)
+
+	_, password, err = credentials.Credentials()
+	if err != nil {
+		t.Fatalf("Failed to read credentials: %v", err)
+	}
+	if password != "second" {
+		t.Fatalf("Rotated password not picked up: %v", password)
+	}
+}
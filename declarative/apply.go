@@ -0,0 +1,74 @@
+package declarative
+
+import (
+	"context"
+	"fmt"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+)
+
+// Apply runs the actions of the plan in order and stops at the first error.
+// The callback, if not nil, is called after every successful action.
+func Apply(ctx context.Context, client manager.Client, plan *Plan, applied func(action *Action)) error {
+	// index of the targets created by the plan, by name
+	created := map[string]int{}
+
+	for _, action := range plan.Actions {
+		if err := applyAction(ctx, client, action, created); err != nil {
+			return fmt.Errorf("failed to %v %v: %w", action.Kind, action.subject(), err)
+		}
+
+		if applied != nil {
+			applied(action)
+		}
+	}
+
+	return nil
+}
+
+func applyAction(ctx context.Context, client manager.Client, action *Action, created map[string]int) error {
+	if action.Target != nil {
+		target, err := client.CreateISCSITargetContext(ctx, action.Target.Name, "")
+		if err != nil {
+			return err
+		}
+
+		created[action.Target.Name] = target.TargetIndex
+		return nil
+	}
+
+	targetIndex := action.TargetIndex
+	if index, ok := created[action.TargetName]; ok && targetIndex < 0 {
+		targetIndex = index
+	}
+
+	switch action.Kind {
+	case ActionKind_Create:
+		want := action.LUN
+
+		lun, err := client.CreateBlockBasedLUNContext(ctx, want.Pool, want.Name, want.SizeGB, want.allocateMode(), want.SSDCache, want.Threshold, nil)
+		if err != nil {
+			return err
+		}
+
+		if targetIndex < 0 {
+			return nil
+		}
+
+		// the volume has to be ready before assigning the LUN
+		if _, err := client.WaitForLUNVolumeContext(ctx, lun.LUNIndex); err != nil {
+			return err
+		}
+
+		return client.AssignLUNContext(ctx, lun.LUNIndex, targetIndex)
+
+	case ActionKind_Update:
+		return client.AssignLUNContext(ctx, action.LUNIndex, targetIndex)
+
+	case ActionKind_Delete:
+		return client.DeleteLUNContext(ctx, action.LUNIndex)
+
+	default:
+		return fmt.Errorf("unknown action: %v", action.Kind)
+	}
+}
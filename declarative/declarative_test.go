@@ -0,0 +1,264 @@
+package declarative
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+	"github.com/nine-lives-later/go-qnap-disk-manager/qnapmock"
+)
+
+const testManifest = `
+targets:
+  - name: k8s
+luns:
+  - name: data01
+    pool: 1
+    size_gb: 10
+    target: k8s
+  - name: data02
+    pool: 1
+    size_gb: 10
+    target: k8s
+  - name: data03
+    pool: 1
+    size_gb: 20
+    allocate: thick
+`
+
+func newTestClient() *qnapmock.Client {
+	lun := func(index int, name string, targetIndex int) *manager.LUN {
+		l := &manager.LUN{LUNIndex: index, LUNName: name, PoolID: 1, CapacityBytes: 10 << 30, LUNThinAllocate: true, LUNThresholdPercent: 80}
+		if targetIndex >= 0 {
+			l.LUNTargetList.SingleRow = &struct {
+				TargetIndex int  `xml:"targetIndex"`
+				LUNNumber   int  `xml:"LUNNumber"`
+				LUNEnable   bool `xml:"LUNEnable"`
+			}{TargetIndex: targetIndex}
+		}
+		return l
+	}
+
+	return &qnapmock.Client{
+		GetISCSITargetsFunc: func() ([]*manager.ISCSITarget, error) {
+			return []*manager.ISCSITarget{{TargetIndex: 0, TargetName: "k8s"}}, nil
+		},
+		GetLUNsFunc: func() ([]*manager.LUN, error) {
+			return []*manager.LUN{
+				lun(1, "data01", 0),  // up-to-date
+				lun(2, "data02", -1), // unassigned
+				lun(7, "old", -1),    // not part of the manifest
+			}, nil
+		},
+		CreateBlockBasedLUNExFunc: func(storagePoolID int, name string, capacityGB int, allocateMode manager.LUNAllocateMode, useSSDCache bool, alertThresoldPercent int, zfsOptions *manager.ZFSLUNOptions) (*manager.LUN, error) {
+			return &manager.LUN{LUNIndex: 8, LUNName: name}, nil
+		},
+	}
+}
+
+func TestNewPlan(t *testing.T) {
+	manifest, err := ParseManifest([]byte(testManifest))
+	if err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+
+	plan, err := NewPlan(context.Background(), newTestClient(), manifest, nil)
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+
+	var b strings.Builder
+	plan.Print(&b)
+	t.Logf("Plan:\n%v", b.String())
+
+	if len(plan.Actions) != 2 {
+		t.Fatalf("Expected 2 actions, got %v", len(plan.Actions))
+	}
+	if a := plan.Actions[0]; a.Kind != ActionKind_Update || a.LUNIndex != 2 || a.TargetIndex != 0 {
+		t.Fatalf("Wrong first action: %v", a)
+	}
+	if a := plan.Actions[1]; a.Kind != ActionKind_Create || a.LUNName != "data03" || a.TargetIndex != -1 {
+		t.Fatalf("Wrong second action: %v", a)
+	}
+
+	// deletes require the explicit opt-in
+	plan, err = NewPlan(context.Background(), newTestClient(), manifest, &PlanOptions{Prune: true})
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	if len(plan.Actions) != 3 || plan.Actions[2].Kind != ActionKind_Delete || plan.Actions[2].LUNIndex != 7 {
+		t.Fatalf("Expected delete of LUN 7 as last action: %v", plan.Actions)
+	}
+}
+
+func TestNewPlan_PruneSkipsSnapshotsAndAssigned(t *testing.T) {
+	manifest, err := ParseManifest([]byte("luns: []\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+
+	client := newTestClient()
+	client.GetLUNsFunc = func() ([]*manager.LUN, error) {
+		luns, _ := newTestClient().GetLUNs()
+		luns = append(luns,
+			&manager.LUN{LUNIndex: 9, LUNName: "old-snapshot", IsSnap: 1},
+			&manager.LUN{LUNIndex: 10, LUNName: "old-removing", IsRemoving: 1})
+		return luns, nil
+	}
+
+	plan, err := NewPlan(context.Background(), client, manifest, &PlanOptions{Prune: true})
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+
+	// data01 is assigned, data02 and old are unassigned
+	if len(plan.Actions) != 2 || plan.Actions[0].LUNIndex != 2 || plan.Actions[1].LUNIndex != 7 {
+		t.Fatalf("Expected to delete LUN 2 and 7 only: %v", plan.Actions)
+	}
+	if len(plan.Warnings) != 2 || !strings.Contains(plan.Warnings[0], "data01") || !strings.Contains(plan.Warnings[1], "old-snapshot") {
+		t.Fatalf("Expected warnings for the assigned LUN and the snapshot: %v", plan.Warnings)
+	}
+}
+
+func TestNewPlan_Drift(t *testing.T) {
+	manifest, err := ParseManifest([]byte("luns:\n  - name: data01\n    pool: 1\n    size_gb: 20\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+
+	plan, err := NewPlan(context.Background(), newTestClient(), manifest, nil)
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	if !plan.Empty() {
+		t.Fatalf("No actions expected: %v", plan.Actions)
+	}
+	if len(plan.Warnings) != 2 { // capacity and unassigning
+		t.Fatalf("Expected 2 warnings, got %v", plan.Warnings)
+	}
+
+	// the SSD cache cannot be changed either
+	manifest.LUNs[0].SizeGB = 10
+	manifest.LUNs[0].SSDCache = true
+
+	plan, err = NewPlan(context.Background(), newTestClient(), manifest, nil)
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	if len(plan.Warnings) != 2 || !strings.Contains(plan.Warnings[0], "SSD cache") {
+		t.Fatalf("Expected warnings for the SSD cache and unassigning, got %v", plan.Warnings)
+	}
+}
+
+func TestNewPlan_CreateTarget(t *testing.T) {
+	manifest, err := ParseManifest([]byte(strings.ReplaceAll(testManifest, "k8s", "backup")))
+	if err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+
+	client := newTestClient()
+	client.CreateISCSITargetFunc = func(name, alias string) (*manager.ISCSITarget, error) {
+		return &manager.ISCSITarget{TargetIndex: 3, TargetName: name}, nil
+	}
+
+	plan, err := NewPlan(context.Background(), client, manifest, nil)
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+
+	var b strings.Builder
+	plan.Print(&b)
+	t.Logf("Plan:\n%v", b.String())
+
+	// the target is created first, the LUN assigned to another target is reported
+	if len(plan.Actions) != 3 || plan.Actions[0].Target == nil || plan.Actions[0].Target.Name != "backup" {
+		t.Fatalf("Expected target creation as first action: %v", plan.Actions)
+	}
+	if a := plan.Actions[1]; a.Kind != ActionKind_Update || a.LUNIndex != 2 || a.TargetName != "backup" {
+		t.Fatalf("Wrong second action: %v", a)
+	}
+	if len(plan.Warnings) != 1 || !strings.Contains(plan.Warnings[0], "reassigning") {
+		t.Fatalf("Expected warning for reassigning data01: %v", plan.Warnings)
+	}
+	if !strings.Contains(b.String(), "+ create iSCSI target backup") || !strings.Contains(b.String(), "Plan: 2 to create, 1 to update, 0 to delete.") {
+		t.Fatalf("Unexpected plan output: %v", b.String())
+	}
+
+	client.Reset()
+
+	if err := Apply(context.Background(), client, plan, nil); err != nil {
+		t.Fatalf("Failed to apply: %v", err)
+	}
+	if calls := client.CallsOf("CreateISCSITargetContext"); len(calls) != 1 || calls[0].Args[1] != "backup" {
+		t.Fatalf("Wrong create target calls: %+v", calls)
+	}
+
+	// the LUNs are assigned to the index of the new target
+	if calls := client.CallsOf("AssignLUNContext"); len(calls) != 1 || calls[0].Args[1] != 2 || calls[0].Args[2] != 3 {
+		t.Fatalf("Wrong assign calls: %+v", calls)
+	}
+}
+
+func TestApply(t *testing.T) {
+	manifest, err := ParseManifest([]byte(testManifest))
+	if err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+	manifest.LUNs[2].Target = "k8s"
+
+	client := newTestClient()
+
+	plan, err := NewPlan(context.Background(), client, manifest, &PlanOptions{Prune: true})
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "apply")
+
+	client.Reset()
+
+	applied := 0
+	if err := Apply(ctx, client, plan, func(*Action) { applied++ }); err != nil {
+		t.Fatalf("Failed to apply: %v", err)
+	}
+	if applied != 3 {
+		t.Fatalf("Expected 3 applied actions, got %v", applied)
+	}
+
+	if calls := client.CallsOf("CreateBlockBasedLUNContext"); len(calls) != 1 || calls[0].Args[2] != "data03" || calls[0].Args[4] != manager.LUNAllocateMode_Thick {
+		t.Fatalf("Wrong create calls: %+v", calls)
+	}
+	if calls := client.CallsOf("AssignLUNContext"); len(calls) != 2 || calls[0].Args[1] != 2 || calls[1].Args[1] != 8 {
+		t.Fatalf("Wrong assign calls: %+v", calls)
+	}
+	if calls := client.CallsOf("WaitForLUNVolumeContext"); len(calls) != 1 {
+		t.Fatalf("Expected to wait for the new LUN volume: %+v", calls)
+	}
+	if calls := client.CallsOf("DeleteLUNContext"); len(calls) != 1 || calls[0].Args[1] != 7 {
+		t.Fatalf("Wrong delete calls: %+v", calls)
+	}
+
+	// the context has to be passed through
+	for _, call := range client.Calls() {
+		if call.Args[0] != ctx {
+			t.Fatalf("Context of %v not passed through", call.Method)
+		}
+	}
+}
+
+func TestParseManifest_Invalid(t *testing.T) {
+	for _, data := range []string{
+		`{"luns": [{"name": "a", "pool": 1, "size_gb": 0}]}`,
+		`{"luns": [{"name": "a", "size_gb": 1}]}`,
+		`{"luns": [{"name": "a", "pool": -1, "size_gb": 1}]}`,
+		`{"luns": [{"name": "a", "pool": 1, "size_gb": 1, "target": "missing"}]}`,
+		`{"luns": [{"name": "a", "pool": 1, "size_gb": 1}, {"name": "a", "pool": 1, "size_gb": 1}]}`,
+		`{"luns": [{"name": "a", "pool": 1, "size_gb": 1, "allocate": "sparse"}]}`,
+	} {
+		if _, err := ParseManifest([]byte(data)); err == nil {
+			t.Fatalf("Error expected for manifest: %v", data)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Package declarative applies a desired state of LUNs and iSCSI target mappings to a QNAP system.
+//
+// The desired state is described by a manifest, which is compared against the
+// current LUNs and targets of the system. The resulting plan lists the actions
+// required to reach the desired state, and can be reviewed before it is applied.
+package declarative
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+)
+
+// Manifest describes the desired state of the LUNs and their target mappings, e.g.:
+//
+//	targets:
+//	  - name: k8s
+//	luns:
+//	  - name: data01
+//	    pool: 1
+//	    size_gb: 100
+//	    allocate: thin
+//	    threshold: 80
+//	    target: k8s
+//
+// The targets and LUNs are matched by name, missing targets are created.
+type Manifest struct {
+	Targets []ManifestTarget `yaml:"targets" json:"targets"`
+	LUNs    []ManifestLUN    `yaml:"luns" json:"luns"`
+}
+
+// ManifestTarget is an iSCSI target referenced by the manifest.
+type ManifestTarget struct {
+	Name string `yaml:"name" json:"name"`
+}
+
+// ManifestLUN is a block-based LUN of the manifest.
+type ManifestLUN struct {
+	Name      string `yaml:"name" json:"name"`
+	Pool      int    `yaml:"pool" json:"pool"`
+	SizeGB    int    `yaml:"size_gb" json:"size_gb"`
+	Allocate  string `yaml:"allocate" json:"allocate"` // thin or thick, defaults to thin
+	SSDCache  bool   `yaml:"ssd_cache" json:"ssd_cache"`
+	Threshold int    `yaml:"threshold" json:"threshold"` // alert threshold in percent, defaults to 80
+	Target    string `yaml:"target" json:"target"`       // name of the target to map the LUN to, empty to keep it unmapped
+}
+
+// ReadManifest reads and validates a manifest file in YAML or JSON format.
+func ReadManifest(file string) (*Manifest, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	return ParseManifest(data)
+}
+
+// ParseManifest parses and validates a manifest in YAML or JSON format.
+func ParseManifest(data []byte) (*Manifest, error) {
+	var manifest Manifest
+
+	// JSON is a subset of YAML
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+}
+
+// Validate checks the manifest for missing and duplicate entries, and applies the defaults.
+func (m *Manifest) Validate() error {
+	targets := map[string]bool{}
+
+	for _, target := range m.Targets {
+		if target.Name == "" {
+			return fmt.Errorf("invalid manifest: target without name")
+		}
+		if targets[target.Name] {
+			return fmt.Errorf("invalid manifest: duplicate target %v", target.Name)
+		}
+		targets[target.Name] = true
+	}
+
+	luns := map[string]bool{}
+
+	for i := range m.LUNs {
+		lun := &m.LUNs[i]
+
+		if lun.Name == "" {
+			return fmt.Errorf("invalid manifest: LUN #%v without name", i+1)
+		}
+		if luns[lun.Name] {
+			return fmt.Errorf("invalid manifest: duplicate LUN %v", lun.Name)
+		}
+		luns[lun.Name] = true
+
+		if lun.Pool <= 0 {
+			return fmt.Errorf("invalid manifest: LUN %v: invalid pool: %v", lun.Name, lun.Pool)
+		}
+		if lun.SizeGB <= 0 {
+			return fmt.Errorf("invalid manifest: LUN %v: invalid size: %v", lun.Name, lun.SizeGB)
+		}
+		if lun.Target != "" && !targets[lun.Target] {
+			return fmt.Errorf("invalid manifest: LUN %v: unknown target %v", lun.Name, lun.Target)
+		}
+
+		switch lun.Allocate {
+		case "":
+			lun.Allocate = "thin"
+		case "thin", "thick":
+		default:
+			return fmt.Errorf("invalid manifest: LUN %v: invalid allocate mode: %v", lun.Name, lun.Allocate)
+		}
+
+		if lun.Threshold == 0 {
+			lun.Threshold = 80
+		}
+	}
+
+	return nil
+}
+
+func (l *ManifestLUN) allocateMode() manager.LUNAllocateMode {
+	if l.Allocate == "thick" {
+		return manager.LUNAllocateMode_Thick
+	}
+	return manager.LUNAllocateMode_Thin
+}
@@ -0,0 +1,277 @@
+package declarative
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+)
+
+type ActionKind string
+
+const (
+	ActionKind_Create ActionKind = "create"
+	ActionKind_Update ActionKind = "update"
+	ActionKind_Delete ActionKind = "delete"
+)
+
+// Action is a single change of the plan, either of a LUN or of an iSCSI target.
+type Action struct {
+	Kind        ActionKind
+	LUNName     string
+	LUNIndex    int             // existing LUN to update or delete, -1 on create
+	LUN         *ManifestLUN    // desired LUN on create and update
+	Target      *ManifestTarget // desired iSCSI target to create, nil for the actions of LUNs
+	TargetIndex int             // target to assign the LUN to, -1 to keep it unassigned or if the target is created by the plan
+	TargetName  string
+}
+
+// String returns a human-readable description of the action.
+func (a *Action) String() string {
+	if a.Target != nil {
+		return fmt.Sprintf("+ create iSCSI target %v", a.Target.Name)
+	}
+
+	switch a.Kind {
+	case ActionKind_Create:
+		desc := fmt.Sprintf("+ create LUN %v (pool %v, %v GB, %v, threshold %v%%)", a.LUNName, a.LUN.Pool, a.LUN.SizeGB, a.LUN.Allocate, a.LUN.Threshold)
+		if a.TargetName != "" {
+			desc += fmt.Sprintf(" and assign to target %v", a.targetDesc())
+		}
+		return desc
+	case ActionKind_Update:
+		return fmt.Sprintf("~ update LUN %v (#%v): assign to target %v", a.LUNName, a.LUNIndex, a.targetDesc())
+	case ActionKind_Delete:
+		return fmt.Sprintf("- delete LUN %v (#%v)", a.LUNName, a.LUNIndex)
+	default:
+		return fmt.Sprintf("? %v LUN %v", a.Kind, a.LUNName)
+	}
+}
+
+// targetDesc describes the target to assign the LUN to, with the index if it exists already.
+func (a *Action) targetDesc() string {
+	if a.TargetIndex < 0 {
+		return fmt.Sprintf("%v (new)", a.TargetName)
+	}
+	return fmt.Sprintf("%v (#%v)", a.TargetName, a.TargetIndex)
+}
+
+// subject names the LUN or iSCSI target changed by the action.
+func (a *Action) subject() string {
+	if a.Target != nil {
+		return "iSCSI target " + a.Target.Name
+	}
+	return "LUN " + a.LUNName
+}
+
+// Plan contains the actions required to reach the desired state of a manifest.
+// Differences which cannot be changed by the QNAP API, e.g. the size of an existing LUN,
+// are reported as warnings.
+type Plan struct {
+	Actions  []*Action
+	Warnings []string
+}
+
+// PlanOptions contains the settings of the planning.
+type PlanOptions struct {
+	Prune bool // delete LUNs which are not part of the manifest, except snapshots and LUNs assigned to a target
+}
+
+// Empty returns true if the plan contains no actions.
+func (p *Plan) Empty() bool {
+	return len(p.Actions) == 0
+}
+
+// Print writes the actions and warnings of the plan.
+func (p *Plan) Print(w io.Writer) error {
+	var b strings.Builder
+
+	for _, warning := range p.Warnings {
+		fmt.Fprintf(&b, "! %v\n", warning)
+	}
+	for _, action := range p.Actions {
+		fmt.Fprintln(&b, action.String())
+	}
+
+	creates, updates, deletes := p.count()
+	fmt.Fprintf(&b, "Plan: %v to create, %v to update, %v to delete.\n", creates, updates, deletes)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (p *Plan) count() (creates, updates, deletes int) {
+	for _, action := range p.Actions {
+		switch action.Kind {
+		case ActionKind_Create:
+			creates++
+		case ActionKind_Update:
+			updates++
+		case ActionKind_Delete:
+			deletes++
+		}
+	}
+	return
+}
+
+// NewPlan compares the manifest against the current LUNs and iSCSI targets of the QNAP system.
+// Missing targets are created first, followed by the creates and updates of the LUNs, deletes last.
+func NewPlan(ctx context.Context, client manager.Client, manifest *Manifest, options *PlanOptions) (*Plan, error) {
+	if options == nil {
+		options = &PlanOptions{}
+	}
+
+	targets, err := client.GetISCSITargetsContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve iSCSI targets: %w", err)
+	}
+	luns, err := client.GetLUNsContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve LUNs: %w", err)
+	}
+
+	// resolve the targets by name
+	targetIndexes := map[string]int{}
+	targetNames := map[int]string{}
+
+	for _, target := range targets {
+		targetIndexes[target.TargetName] = target.TargetIndex
+		targetNames[target.TargetIndex] = target.TargetName
+	}
+
+	// index the current LUNs by name
+	current := map[string]*manager.LUN{}
+
+	for _, lun := range luns {
+		if other, ok := current[lun.LUNName]; ok {
+			return nil, fmt.Errorf("LUN name %v is not unique (LUN #%v and #%v)", lun.LUNName, other.LUNIndex, lun.LUNIndex)
+		}
+		current[lun.LUNName] = lun
+	}
+
+	plan := &Plan{}
+	desired := map[string]bool{}
+
+	// create the missing targets before assigning any LUN
+	for i := range manifest.Targets {
+		want := &manifest.Targets[i]
+		if _, ok := targetIndexes[want.Name]; ok {
+			continue
+		}
+
+		plan.Actions = append(plan.Actions, &Action{
+			Kind:        ActionKind_Create,
+			LUNIndex:    -1,
+			Target:      want,
+			TargetIndex: -1,
+			TargetName:  want.Name,
+		})
+	}
+
+	for i := range manifest.LUNs {
+		want := &manifest.LUNs[i]
+		desired[want.Name] = true
+
+		targetIndex := -1
+		if index, ok := targetIndexes[want.Target]; ok && want.Target != "" {
+			targetIndex = index
+		}
+
+		have, ok := current[want.Name]
+		if !ok {
+			plan.Actions = append(plan.Actions, &Action{
+				Kind:        ActionKind_Create,
+				LUNName:     want.Name,
+				LUNIndex:    -1,
+				LUN:         want,
+				TargetIndex: targetIndex,
+				TargetName:  want.Target,
+			})
+			continue
+		}
+
+		plan.Warnings = append(plan.Warnings, drift(want, have)...)
+
+		// target mapping
+		haveTarget := -1
+		if have.LUNTargetList.SingleRow != nil {
+			haveTarget = have.LUNTargetList.SingleRow.TargetIndex
+		}
+
+		switch {
+		case targetNames[haveTarget] == want.Target: // unassigned LUNs have no target name
+		case haveTarget < 0:
+			plan.Actions = append(plan.Actions, &Action{
+				Kind:        ActionKind_Update,
+				LUNName:     want.Name,
+				LUNIndex:    have.LUNIndex,
+				LUN:         want,
+				TargetIndex: targetIndex,
+				TargetName:  want.Target,
+			})
+		case want.Target == "":
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("LUN %v: unassigning from target %v is not supported", want.Name, targetNames[haveTarget]))
+		default:
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("LUN %v: reassigning from target %v to %v is not supported", want.Name, targetNames[haveTarget], want.Target))
+		}
+	}
+
+	// delete the LUNs not part of the manifest
+	if options.Prune {
+		var unknown []*manager.LUN
+
+		for _, lun := range luns {
+			if !desired[lun.LUNName] && lun.IsRemoving == 0 {
+				unknown = append(unknown, lun)
+			}
+		}
+		sort.Slice(unknown, func(i, j int) bool { return unknown[i].LUNIndex < unknown[j].LUNIndex })
+
+		for _, lun := range unknown {
+			// never delete snapshots or LUNs in use
+			if lun.IsSnap != 0 {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("LUN %v (#%v): snapshot is not pruned", lun.LUNName, lun.LUNIndex))
+				continue
+			}
+			if lun.LUNTargetList.SingleRow != nil {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("LUN %v (#%v): assigned to target %v, it is not pruned", lun.LUNName, lun.LUNIndex, targetNames[lun.LUNTargetList.SingleRow.TargetIndex]))
+				continue
+			}
+
+			plan.Actions = append(plan.Actions, &Action{
+				Kind:        ActionKind_Delete,
+				LUNName:     lun.LUNName,
+				LUNIndex:    lun.LUNIndex,
+				TargetIndex: -1,
+			})
+		}
+	}
+
+	return plan, nil
+}
+
+// drift returns the differences of an existing LUN, which cannot be changed.
+func drift(want *ManifestLUN, have *manager.LUN) []string {
+	var warnings []string
+
+	if have.PoolID != want.Pool {
+		warnings = append(warnings, fmt.Sprintf("LUN %v: pool %v differs from %v and cannot be changed", want.Name, have.PoolID, want.Pool))
+	}
+	if have.CapacityBytes != int64(want.SizeGB)*1024*1024*1024 {
+		warnings = append(warnings, fmt.Sprintf("LUN %v: capacity of %v bytes differs from %v GB and cannot be changed", want.Name, have.CapacityBytes, want.SizeGB))
+	}
+	if have.LUNThinAllocate != (want.allocateMode() == manager.LUNAllocateMode_Thin) {
+		warnings = append(warnings, fmt.Sprintf("LUN %v: allocate mode differs from %v and cannot be changed", want.Name, want.Allocate))
+	}
+	if ssdCache := have.SsdCache == "yes" || have.SsdCache == "1"; ssdCache != want.SSDCache {
+		warnings = append(warnings, fmt.Sprintf("LUN %v: SSD cache %v differs from %v and cannot be changed", want.Name, ssdCache, want.SSDCache))
+	}
+	if have.LUNThresholdPercent != want.Threshold {
+		warnings = append(warnings, fmt.Sprintf("LUN %v: threshold %v%% differs from %v%% and cannot be changed", want.Name, have.LUNThresholdPercent, want.Threshold))
+	}
+
+	return warnings
+}
@@ -1,9 +1,16 @@
 package manager
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"strconv"
+	"sync"
 	"time"
+
+	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type LUNAllocateMode string
@@ -31,42 +38,161 @@ type getStoragePoolListResponse struct {
 	Result string `xml:"result"`
 }
 
+// StoragePoolOptions contains the settings of retrieving the storage pools.
+type StoragePoolOptions struct {
+	Concurrency    int  // number of pools retrieved in parallel, defaults to 4
+	PartialResults bool // return the retrieved pools together with the error of the failed ones, instead of no pools
+}
+
+var defaultStoragePoolOptions = StoragePoolOptions{
+	Concurrency: 4,
+}
+
+// StoragePoolError is the error of retrieving the information of a single storage pool.
+// Multiple errors are joined, use errors.As() to inspect them.
+type StoragePoolError struct {
+	PoolID int
+	Err    error
+}
+
+func (e *StoragePoolError) Error() string {
+	return fmt.Sprintf("failed to retrieve storage pool information for pool #%v: %v", e.PoolID, e.Err)
+}
+
+func (e *StoragePoolError) Unwrap() error {
+	return e.Err
+}
+
 // GetStoragePools retrieves the list of storage pools.
 func (s *QnapSession) GetStoragePools() ([]*StoragePool, error) {
+	return s.GetStoragePoolsExContext(context.Background(), nil)
+}
+
+// GetStoragePoolsContext retrieves the list of storage pools.
+func (s *QnapSession) GetStoragePoolsContext(ctx context.Context) ([]*StoragePool, error) {
+	return s.GetStoragePoolsExContext(ctx, nil)
+}
+
+// GetStoragePoolsEx retrieves the list of storage pools, ordered like returned by the QNAP system.
+func (s *QnapSession) GetStoragePoolsEx(options *StoragePoolOptions) ([]*StoragePool, error) {
+	return s.GetStoragePoolsExContext(context.Background(), options)
+}
+
+// GetStoragePoolsExContext retrieves the list of storage pools, ordered like returned by the QNAP system.
+// The information of the pools is retrieved in parallel, one request per pool.
+func (s *QnapSession) GetStoragePoolsExContext(ctx context.Context, options *StoragePoolOptions) (_ []*StoragePool, err error) {
+	ctx, span := s.startSpan(ctx, "GetStoragePools")
+	defer func() { endSpan(span, err) }()
+
+	if cached, ok := s.cache.getStoragePools(); ok {
+		span.SetAttributes(attribute.Bool("qnap.cache_hit", true))
+		return cached, nil
+	}
+	generation := s.cache.start()
+
+	o := defaultStoragePoolOptions
+	if options != nil {
+		o = *options
+
+		if o.Concurrency <= 0 {
+			o.Concurrency = defaultStoragePoolOptions.Concurrency
+		}
+	}
+
+	poolIDs, err := s.getStoragePoolIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// read the pool info for every pool
+	pools := make([]*StoragePool, len(poolIDs))
+	errs := make([]error, len(poolIDs))
+
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, o.Concurrency)
+
+	for i, poolID := range poolIDs {
+		wg.Add(1)
+		go func(i, poolID int) {
+			defer wg.Done()
+
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
+			info, err := s.getStoragePoolInfo(ctx, poolID)
+			if err != nil {
+				errs[i] = &StoragePoolError{PoolID: poolID, Err: err}
+				return
+			}
+			pools[i] = info
+		}(i, poolID)
+	}
+
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		if !o.PartialResults {
+			return nil, err
+		}
+
+		retrieved := make([]*StoragePool, 0, len(pools))
+		for _, pool := range pools {
+			if pool != nil {
+				retrieved = append(retrieved, pool)
+			}
+		}
+		return retrieved, err
+	}
+
+	s.cache.putStoragePools(generation, pools)
+
+	return pools, nil
+}
+
+// GetStoragePoolIDs retrieves the IDs of the storage pools, without their information.
+func (s *QnapSession) GetStoragePoolIDs() ([]int, error) {
+	return s.GetStoragePoolIDsContext(context.Background())
+}
+
+// GetStoragePoolIDsContext retrieves the IDs of the storage pools, without their information.
+func (s *QnapSession) GetStoragePoolIDsContext(ctx context.Context) (_ []int, err error) {
+	ctx, span := s.startSpan(ctx, "GetStoragePoolIDs")
+	defer func() { endSpan(span, err) }()
+
+	return s.getStoragePoolIDs(ctx)
+}
+
+func (s *QnapSession) getStoragePoolIDs(ctx context.Context) ([]int, error) {
 	var result getStoragePoolListResponse
 
-	res, err := s.conn.NewRequest().
+	req := s.conn.NewRequest().
+		SetContext(ctx).
 		ExpectContentType("text/xml").
 		SetQueryParam("store", "poolList").
 		SetQueryParam("func", "extra_get").
 		SetQueryParam("extra_pool_index", "1").
-		SetResult(&result).
-		Post("cgi-bin/disk/disk_manage.cgi")
+		SetResult(&result)
+
+	res, err := s.execute(req, resty.MethodPost, "cgi-bin/disk/disk_manage.cgi")
 	if err != nil {
-		return nil, fmt.Errorf("failed to perform request: %v", err)
+		return nil, fmt.Errorf("failed to perform request: %w", err)
 	}
 	if res.StatusCode() != 200 {
 		return nil, fmt.Errorf("failed to perform request: unexpected HTTP status code: %v", res.StatusCode())
 	}
 	if result.AuthPassed != 1 {
-		return nil, fmt.Errorf("failed to perform request: authentication invalid: %v", string(res.Body()))
+		return nil, fmt.Errorf("failed to perform request: %w: %v", errAuthenticationInvalid, redact(string(res.Body())))
 	}
 	if result.Result != "0" {
 		return nil, fmt.Errorf("failed to perform request: unexpected result code: %v", result.Result)
 	}
 
-	// read the pool info for every pool
-	pools := make([]*StoragePool, 0)
-
-	for _, poolID := range result.PoolIndex.Row {
-		info, err := s.getStoragePoolInfo(poolID.PoolID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to retrieve storage pool information for pool #%v: %w", poolID.PoolID, err)
-		}
-		pools = append(pools, info)
+	poolIDs := make([]int, 0, len(result.PoolIndex.Row))
+	for _, row := range result.PoolIndex.Row {
+		poolIDs = append(poolIDs, row.PoolID)
 	}
 
-	return pools, nil
+	return poolIDs, nil
 }
 
 type StoragePool struct {
@@ -114,25 +240,30 @@ type getStoragePoolInfoResponse struct {
 	Result string `xml:"result"`
 }
 
-func (s *QnapSession) getStoragePoolInfo(poolID int) (*StoragePool, error) {
+func (s *QnapSession) getStoragePoolInfo(ctx context.Context, poolID int) (_ *StoragePool, err error) {
+	ctx, span := s.startSpan(ctx, "getStoragePoolInfo", attribute.Int("qnap.pool_id", poolID))
+	defer func() { endSpan(span, err) }()
+
 	var result getStoragePoolInfoResponse
 
-	res, err := s.conn.NewRequest().
+	req := s.conn.NewRequest().
+		SetContext(ctx).
 		ExpectContentType("text/xml").
 		SetQueryParam("store", "poolInfo").
 		SetQueryParam("func", "extra_get").
 		SetQueryParam("Pool_Info", "1").
 		SetQueryParam("poolID", strconv.Itoa(poolID)).
-		SetResult(&result).
-		Post("cgi-bin/disk/disk_manage.cgi")
+		SetResult(&result)
+
+	res, err := s.execute(req, resty.MethodPost, "cgi-bin/disk/disk_manage.cgi")
 	if err != nil {
-		return nil, fmt.Errorf("failed to perform request: %v", err)
+		return nil, fmt.Errorf("failed to perform request: %w", err)
 	}
 	if res.StatusCode() != 200 {
 		return nil, fmt.Errorf("failed to perform request: unexpected HTTP status code: %v", res.StatusCode())
 	}
 	if result.AuthPassed != 1 {
-		return nil, fmt.Errorf("failed to perform request: authentication invalid: %v", string(res.Body()))
+		return nil, fmt.Errorf("failed to perform request: %w: %v", errAuthenticationInvalid, redact(string(res.Body())))
 	}
 	if result.Result != "0" {
 		return nil, fmt.Errorf("failed to perform request: unexpected result code: %v", result.Result)
@@ -151,16 +282,62 @@ type createBlockBasedLUNResponse struct {
 	LUNIndex   int    `xml:"result"`
 }
 
+// ZFSLUNOptions contains the ZFS specific LUN settings, only supported by QuTS hero.
+// The settings are sent as the "compression", "dedup" and "block_size" parameters, which are
+// not covered by any public QNAP API documentation and are not verified against a real NAS yet.
+// Snapshots of ZFS LUNs are out of scope, the library has no snapshot operations.
+type ZFSLUNOptions struct {
+	Compression   bool
+	Deduplication bool
+	BlockSizeKB   int // zero uses the NAS default
+}
+
 // CreateBlockBasedLUN creates a new block-based volume inside a storage pool and returns the new LUN.
 func (s *QnapSession) CreateBlockBasedLUN(storagePoolID int, name string, capacityGB int, allocateMode LUNAllocateMode, useSSDCache bool, alertThresoldPercent int) (*LUN, error) {
+	return s.CreateBlockBasedLUNContext(context.Background(), storagePoolID, name, capacityGB, allocateMode, useSSDCache, alertThresoldPercent, nil)
+}
+
+// CreateBlockBasedLUNEx creates a new block-based volume inside a storage pool and returns the new LUN.
+// The ZFS options are only supported by QuTS hero and may be nil to use the NAS defaults.
+func (s *QnapSession) CreateBlockBasedLUNEx(storagePoolID int, name string, capacityGB int, allocateMode LUNAllocateMode, useSSDCache bool, alertThresoldPercent int, zfsOptions *ZFSLUNOptions) (*LUN, error) {
+	return s.CreateBlockBasedLUNContext(context.Background(), storagePoolID, name, capacityGB, allocateMode, useSSDCache, alertThresoldPercent, zfsOptions)
+}
+
+// CreateBlockBasedLUNContext creates a new block-based volume inside a storage pool and returns the new LUN.
+// The ZFS options are only supported by QuTS hero and may be nil to use the NAS defaults.
+func (s *QnapSession) CreateBlockBasedLUNContext(ctx context.Context, storagePoolID int, name string, capacityGB int, allocateMode LUNAllocateMode, useSSDCache bool, alertThresoldPercent int, zfsOptions *ZFSLUNOptions) (_ *LUN, err error) {
+	ctx, span := s.startSpan(ctx, "CreateBlockBasedLUN",
+		attribute.Int("qnap.pool_id", storagePoolID),
+		attribute.String("qnap.lun_name", name),
+		attribute.Int("qnap.capacity_gb", capacityGB))
+	defer func() { endSpan(span, err) }()
+
 	var result createBlockBasedLUNResponse
 
+	if err := s.requireAdmin("create LUN"); err != nil {
+		return nil, err
+	}
+
+	capabilities := s.Capabilities()
+	isZFS := capabilities.OSFlavor == OSFlavor_QuTSHero
+
+	if zfsOptions != nil && !isZFS {
+		if !s.firmwareDetected() {
+			return nil, fmt.Errorf("ZFS options are only supported by %v, but the firmware version is unknown; set ConfigOptions.Capabilities", OSFlavor_QuTSHero)
+		}
+		return nil, fmt.Errorf("ZFS options are only supported by %v, but connected to %v", OSFlavor_QuTSHero, capabilities.OSFlavor)
+	}
+
+	// keep other changes out until the new LUN is visible
+	defer s.lockMutations()()
+
 	useSSDCacheStr := "no"
 	if useSSDCache {
 		useSSDCacheStr = "yes"
 	}
 
-	res, err := s.conn.NewRequest().
+	req := s.conn.NewRequest().
+		SetContext(ctx).
 		ExpectContentType("text/xml").
 		SetQueryParam("func", "add_lun").
 		SetQueryParam("LUNThinAllocate", string(allocateMode)).
@@ -175,26 +352,50 @@ func (s *QnapSession) CreateBlockBasedLUN(storagePoolID int, name string, capaci
 		SetQueryParam("LUNPath", name).
 		SetQueryParam("enable_tiering", "0").
 		SetQueryParam("lv_threshold", strconv.Itoa(alertThresoldPercent)).
-		SetResult(&result).
-		Post("cgi-bin/disk/iscsi_lun_setting.cgi")
+		SetResult(&result)
+
+	// QuTS hero creates the LUN as ZFS zvol; there is no public API documentation of these
+	// parameters, the names follow the fields of the LUN wizard of the QuTS hero web UI and
+	// have not been verified against a real NAS yet
+	if isZFS {
+		req.SetQueryParam("backend", capabilities.ISCSIBackend)
+
+		if zfsOptions != nil {
+			req.SetQueryParam("compression", boolToIntStr(zfsOptions.Compression))
+			req.SetQueryParam("dedup", boolToIntStr(zfsOptions.Deduplication))
+
+			if zfsOptions.BlockSizeKB > 0 {
+				req.SetQueryParam("block_size", strconv.Itoa(zfsOptions.BlockSizeKB))
+			}
+		}
+	}
+
+	res, err := s.executeMutation(req, resty.MethodPost, "cgi-bin/disk/iscsi_lun_setting.cgi")
 	if err != nil {
-		return nil, fmt.Errorf("failed to perform request: %v", err)
+		return nil, fmt.Errorf("failed to perform request: %w", err)
 	}
 	if res.StatusCode() != 200 {
 		return nil, fmt.Errorf("failed to perform request: unexpected HTTP status code: %v", res.StatusCode())
 	}
 	if result.AuthPassed != 1 {
-		return nil, fmt.Errorf("failed to perform request: authentication invalid: %v", string(res.Body()))
+		return nil, fmt.Errorf("failed to perform request: %w: %v", errAuthenticationInvalid, redact(string(res.Body())))
 	}
 
+	span.SetAttributes(attribute.Int("qnap.lun_index", result.LUNIndex))
+
 	// find the lun (need to try several times)
 	for try := 1; try <= 30; try++ {
-		time.Sleep(2 * time.Second) // wait two seconds
+		if err := sleepContext(ctx, 2*time.Second); err != nil { // wait two seconds
+			return nil, fmt.Errorf("failed to find LUN %v: %w", result.LUNIndex, err)
+		}
 
-		lun, err := s.GetLUNByIndex(result.LUNIndex)
+		lun, err := s.GetLUNByIndexContext(ctx, result.LUNIndex)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get LUN %v: %w", result.LUNIndex, err)
 		}
+
+		s.log(slog.LevelDebug, "Waiting for new LUN", "lunIndex", result.LUNIndex, "try", try, "found", lun != nil)
+
 		if lun != nil {
 			return lun, nil
 		}
@@ -255,28 +456,46 @@ type getStorageLUNsResponse struct {
 
 // GetLUNs retrieves the list of all storage LUNs.
 func (s *QnapSession) GetLUNs() ([]*LUN, error) {
+	return s.GetLUNsContext(context.Background())
+}
+
+// GetLUNsContext retrieves the list of all storage LUNs.
+func (s *QnapSession) GetLUNsContext(ctx context.Context) (_ []*LUN, err error) {
+	ctx, span := s.startSpan(ctx, "GetLUNs")
+	defer func() { endSpan(span, err) }()
+
+	if cached, ok := s.cache.getLUNs(); ok {
+		span.SetAttributes(attribute.Bool("qnap.cache_hit", true))
+		return cached, nil
+	}
+	generation := s.cache.start()
+
 	var result getStorageLUNsResponse
 
-	res, err := s.conn.NewRequest().
+	req := s.conn.NewRequest().
+		SetContext(ctx).
 		ExpectContentType("text/xml").
 		SetQueryParam("store", "storageSpace_LUNList").
 		SetQueryParam("func", "extra_get").
 		SetQueryParam("lunList", "1").
-		SetResult(&result).
-		Post("cgi-bin/disk/iscsi_portal_setting.cgi")
+		SetResult(&result)
+
+	res, err := s.execute(req, resty.MethodPost, "cgi-bin/disk/iscsi_portal_setting.cgi")
 	if err != nil {
-		return nil, fmt.Errorf("failed to perform request: %v", err)
+		return nil, fmt.Errorf("failed to perform request: %w", err)
 	}
 	if res.StatusCode() != 200 {
 		return nil, fmt.Errorf("failed to perform request: unexpected HTTP status code: %v", res.StatusCode())
 	}
 	if result.AuthPassed != 1 {
-		return nil, fmt.Errorf("failed to perform request: authentication invalid: %v", string(res.Body()))
+		return nil, fmt.Errorf("failed to perform request: %w: %v", errAuthenticationInvalid, redact(string(res.Body())))
 	}
 	if result.Result != "0" {
 		return nil, fmt.Errorf("failed to perform request: unexpected result code: %v", result.Result)
 	}
 
+	s.cache.putLUNs(generation, result.ISCSILUNList.LUNInfo)
+
 	return result.ISCSILUNList.LUNInfo, nil
 }
 
@@ -291,24 +510,34 @@ type getLUNByID struct {
 
 // GetLUNByIndex retrieves the a storage LUN by its LUN ID (not volume ID!)
 func (s *QnapSession) GetLUNByIndex(lunIndex int) (*LUN, error) {
+	return s.GetLUNByIndexContext(context.Background(), lunIndex)
+}
+
+// GetLUNByIndexContext retrieves the a storage LUN by its LUN ID (not volume ID!)
+func (s *QnapSession) GetLUNByIndexContext(ctx context.Context, lunIndex int) (_ *LUN, err error) {
+	ctx, span := s.startSpan(ctx, "GetLUNByIndex", attribute.Int("qnap.lun_index", lunIndex))
+	defer func() { endSpan(span, err) }()
+
 	var result getLUNByID
 
-	res, err := s.conn.NewRequest().
+	req := s.conn.NewRequest().
+		SetContext(ctx).
 		ExpectContentType("text/xml").
 		SetQueryParam("store", "lunInfo").
 		SetQueryParam("lunID", strconv.Itoa(lunIndex)).
 		SetQueryParam("func", "extra_get").
 		SetQueryParam("lun_info", "1").
-		SetResult(&result).
-		Post("cgi-bin/disk/iscsi_portal_setting.cgi")
+		SetResult(&result)
+
+	res, err := s.execute(req, resty.MethodPost, "cgi-bin/disk/iscsi_portal_setting.cgi")
 	if err != nil {
-		return nil, fmt.Errorf("failed to perform request: %v", err)
+		return nil, fmt.Errorf("failed to perform request: %w", err)
 	}
 	if res.StatusCode() != 200 {
 		return nil, fmt.Errorf("failed to perform request: unexpected HTTP status code: %v", res.StatusCode())
 	}
 	if result.AuthPassed != 1 {
-		return nil, fmt.Errorf("failed to perform request: authentication invalid: %v", string(res.Body()))
+		return nil, fmt.Errorf("failed to perform request: %w: %v", errAuthenticationInvalid, redact(string(res.Body())))
 	}
 	if result.Result != "0" {
 		return nil, fmt.Errorf("failed to perform request: unexpected result code: %v", result.Result)
@@ -323,30 +552,46 @@ type genericResponse struct {
 	Result     string `xml:"result"`
 }
 
-// DeleteLUN retrieves the a storage LUN by its LUN ID (not volume ID!)
+// DeleteLUN deletes the storage LUN by its LUN ID (not volume ID!)
 func (s *QnapSession) DeleteLUN(lunID int) error {
+	return s.DeleteLUNContext(context.Background(), lunID)
+}
+
+// DeleteLUNContext deletes the storage LUN by its LUN ID (not volume ID!)
+func (s *QnapSession) DeleteLUNContext(ctx context.Context, lunID int) (err error) {
+	ctx, span := s.startSpan(ctx, "DeleteLUN", attribute.Int("qnap.lun_index", lunID))
+	defer func() { endSpan(span, err) }()
+
 	var result genericResponse
 
-	res, err := s.conn.NewRequest().
+	if err := s.requireAdmin("delete LUN"); err != nil {
+		return err
+	}
+
+	defer s.lockMutations()()
+
+	req := s.conn.NewRequest().
+		SetContext(ctx).
 		ExpectContentType("text/xml").
-		SetQueryParam("prod", "qts").
+		SetQueryParam("prod", s.Capabilities().ISCSIProduct).
 		SetQueryParam("proto", "iscsi").
-		SetQueryParam("target", "lio").
-		SetQueryParam("backend", "dm").
+		SetQueryParam("target", s.Capabilities().ISCSITarget).
+		SetQueryParam("backend", s.Capabilities().ISCSIBackend).
 		SetQueryParam("conf", "init").
 		SetQueryParam("func", "remove_lun").
 		SetQueryParam("run_background", "1").
 		SetQueryParam("LUNIndex", strconv.Itoa(lunID)).
-		SetResult(&result).
-		Post("cgi-bin/disk/iscsi_lun_setting.cgi")
+		SetResult(&result)
+
+	res, err := s.executeMutation(req, resty.MethodPost, "cgi-bin/disk/iscsi_lun_setting.cgi")
 	if err != nil {
-		return fmt.Errorf("failed to perform request: %v", err)
+		return fmt.Errorf("failed to perform request: %w", err)
 	}
 	if res.StatusCode() != 200 {
 		return fmt.Errorf("failed to perform request: unexpected HTTP status code: %v", res.StatusCode())
 	}
 	if result.AuthPassed != 1 {
-		return fmt.Errorf("failed to perform request: authentication invalid: %v", string(res.Body()))
+		return fmt.Errorf("failed to perform request: %w: %v", errAuthenticationInvalid, redact(string(res.Body())))
 	}
 	if result.Result != "0" {
 		return fmt.Errorf("failed to perform request: unexpected result code: %v", result.Result)
@@ -357,19 +602,35 @@ func (s *QnapSession) DeleteLUN(lunID int) error {
 
 // WaitForLUNVolume waits for the volume of the LUN to become ready.
 func (s *QnapSession) WaitForLUNVolume(lunID int) (*LUN, error) {
+	return s.WaitForLUNVolumeContext(context.Background(), lunID)
+}
+
+// WaitForLUNVolumeContext waits for the volume of the LUN to become ready.
+func (s *QnapSession) WaitForLUNVolumeContext(ctx context.Context, lunID int) (_ *LUN, err error) {
+	ctx, span := s.startSpan(ctx, "WaitForLUNVolume", attribute.Int("qnap.lun_index", lunID))
+	defer func() { endSpan(span, err) }()
+
+	// the NAS is still busy creating the volume, so keep other changes out
+	defer s.lockMutations()()
+
 	for try := 1; try <= 30; try++ {
-		lun, err := s.GetLUNByIndex(lunID)
+		lun, err := s.GetLUNByIndexContext(ctx, lunID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get LUN %v: %w", lunID, err)
 		}
 		if lun == nil {
 			return nil, fmt.Errorf("LUN not found: %v", lunID)
 		}
+
+		s.log(slog.LevelDebug, "Waiting for LUN volume", "lunIndex", lunID, "try", try, "volumeID", lun.VolumeID)
+
 		if lun.VolumeID >= 0 { // volume is ready
 			return lun, nil
 		}
 
-		time.Sleep(2 * time.Second) // wait two seconds
+		if err := sleepContext(ctx, 2*time.Second); err != nil { // wait two seconds
+			return nil, fmt.Errorf("failed to wait for LUN %v volume to become ready: %w", lunID, err)
+		}
 	}
 
 	return nil, fmt.Errorf("failed to wait for LUN %v volume to become ready (timeout)", lunID)
@@ -377,34 +638,145 @@ func (s *QnapSession) WaitForLUNVolume(lunID int) (*LUN, error) {
 
 // AssignLUN assigns an existing LUN to an existing iSCSI target
 func (s *QnapSession) AssignLUN(lunIndex int, targetIndex int) error {
+	return s.AssignLUNContext(context.Background(), lunIndex, targetIndex)
+}
+
+// AssignLUNContext assigns an existing LUN to an existing iSCSI target
+func (s *QnapSession) AssignLUNContext(ctx context.Context, lunIndex int, targetIndex int) (err error) {
+	ctx, span := s.startSpan(ctx, "AssignLUN", attribute.Int("qnap.lun_index", lunIndex), attribute.Int("qnap.target_index", targetIndex))
+	defer func() { endSpan(span, err) }()
+
 	var result genericResponse
 
-	res, err := s.conn.NewRequest().
+	if err := s.requireAdmin("assign LUN"); err != nil {
+		return err
+	}
+
+	defer s.lockMutations()()
+
+	req := s.conn.NewRequest().
+		SetContext(ctx).
 		ExpectContentType("text/xml").
-		SetQueryParam("prod", "qts").
+		SetQueryParam("prod", s.Capabilities().ISCSIProduct).
 		SetQueryParam("proto", "iscsi").
-		SetQueryParam("target", "lio").
-		SetQueryParam("backend", "dm").
+		SetQueryParam("target", s.Capabilities().ISCSITarget).
+		SetQueryParam("backend", s.Capabilities().ISCSIBackend).
 		SetQueryParam("conf", "ini").
 		SetQueryParam("func", "add_lun").
 		SetQueryParam("LUNIndex", strconv.Itoa(lunIndex)).
 		SetQueryParam("targetIndex", strconv.Itoa(targetIndex)).
-		SetResult(&result).
-		Post("cgi-bin/disk/iscsi_target_setting.cgi")
+		SetResult(&result)
+
+	res, err := s.executeMutation(req, resty.MethodPost, "cgi-bin/disk/iscsi_target_setting.cgi")
 	if err != nil {
-		return fmt.Errorf("failed to perform request: %v", err)
+		return fmt.Errorf("failed to perform request: %w", err)
 	}
 	if res.StatusCode() != 200 {
 		return fmt.Errorf("failed to perform request: unexpected HTTP status code: %v", res.StatusCode())
 	}
 	if result.AuthPassed != 1 {
-		return fmt.Errorf("failed to perform request: authentication invalid: %v", string(res.Body()))
+		return fmt.Errorf("failed to perform request: %w: %v", errAuthenticationInvalid, redact(string(res.Body())))
 	}
 	// do not check for result.Result as it contains the LUN LUNIndex within the iSCSI target
 
 	return nil
 }
 
+// UnassignLUN removes an existing LUN from an iSCSI target
+func (s *QnapSession) UnassignLUN(lunIndex int, targetIndex int) error {
+	return s.UnassignLUNContext(context.Background(), lunIndex, targetIndex)
+}
+
+// UnassignLUNContext removes an existing LUN from an iSCSI target
+func (s *QnapSession) UnassignLUNContext(ctx context.Context, lunIndex int, targetIndex int) (err error) {
+	ctx, span := s.startSpan(ctx, "UnassignLUN", attribute.Int("qnap.lun_index", lunIndex), attribute.Int("qnap.target_index", targetIndex))
+	defer func() { endSpan(span, err) }()
+
+	var result genericResponse
+
+	if err := s.requireAdmin("unassign LUN"); err != nil {
+		return err
+	}
+
+	defer s.lockMutations()()
+
+	req := s.conn.NewRequest().
+		SetContext(ctx).
+		ExpectContentType("text/xml").
+		SetQueryParam("prod", s.Capabilities().ISCSIProduct).
+		SetQueryParam("proto", "iscsi").
+		SetQueryParam("target", s.Capabilities().ISCSITarget).
+		SetQueryParam("backend", s.Capabilities().ISCSIBackend).
+		SetQueryParam("conf", "ini").
+		SetQueryParam("func", "remove_lun").
+		SetQueryParam("LUNIndex", strconv.Itoa(lunIndex)).
+		SetQueryParam("targetIndex", strconv.Itoa(targetIndex)).
+		SetResult(&result)
+
+	res, err := s.executeMutation(req, resty.MethodPost, "cgi-bin/disk/iscsi_target_setting.cgi")
+	if err != nil {
+		return fmt.Errorf("failed to perform request: %w", err)
+	}
+	if res.StatusCode() != 200 {
+		return fmt.Errorf("failed to perform request: unexpected HTTP status code: %v", res.StatusCode())
+	}
+	if result.AuthPassed != 1 {
+		return fmt.Errorf("failed to perform request: %w: %v", errAuthenticationInvalid, redact(string(res.Body())))
+	}
+	if result.Result != "0" {
+		return fmt.Errorf("failed to perform request: unexpected result code: %v", result.Result)
+	}
+
+	return nil
+}
+
+// AddLUNInitiator adds an initiator ACL entry to an existing LUN, restricting the access of the initiator.
+func (s *QnapSession) AddLUNInitiator(lunIndex int, initiatorIQN string, accessMode int) error {
+	return s.AddLUNInitiatorContext(context.Background(), lunIndex, initiatorIQN, accessMode)
+}
+
+// AddLUNInitiatorContext adds an initiator ACL entry to an existing LUN, restricting the access of the initiator.
+// The access mode is the value reported in LUN.LUNInitiatorList.
+func (s *QnapSession) AddLUNInitiatorContext(ctx context.Context, lunIndex int, initiatorIQN string, accessMode int) (err error) {
+	ctx, span := s.startSpan(ctx, "AddLUNInitiator", attribute.Int("qnap.lun_index", lunIndex))
+	defer func() { endSpan(span, err) }()
+
+	var result genericResponse
+
+	if err := s.requireAdmin("add LUN initiator"); err != nil {
+		return err
+	}
+
+	defer s.lockMutations()()
+
+	// there is no public API documentation of the ACL call, the parameters follow the
+	// fields of the LUN listing and have not been verified against a real NAS yet
+	req := s.conn.NewRequest().
+		SetContext(ctx).
+		ExpectContentType("text/xml").
+		SetQueryParam("func", "add_init").
+		SetQueryParam("LUNIndex", strconv.Itoa(lunIndex)).
+		SetQueryParam("initiatorIQN", initiatorIQN).
+		SetQueryParam("accessMode", strconv.Itoa(accessMode)).
+		SetResult(&result)
+
+	res, err := s.executeMutation(req, resty.MethodPost, "cgi-bin/disk/iscsi_lun_setting.cgi")
+	if err != nil {
+		return fmt.Errorf("failed to perform request: %w", err)
+	}
+	if res.StatusCode() != 200 {
+		return fmt.Errorf("failed to perform request: unexpected HTTP status code: %v", res.StatusCode())
+	}
+	if result.AuthPassed != 1 {
+		return fmt.Errorf("failed to perform request: %w: %v", errAuthenticationInvalid, redact(string(res.Body())))
+	}
+	if result.Result != "0" {
+		return fmt.Errorf("failed to perform request: unexpected result code: %v", result.Result)
+	}
+
+	return nil
+}
+
 type ISCSITarget struct {
 	TargetIndex  int    `xml:"targetIndex"`
 	TargetName   string `xml:"targetName"`
@@ -424,31 +796,176 @@ type getISCSITargetsResponse struct {
 
 // GetISCSITargets retrieves the list of all iSCSI targets.
 func (s *QnapSession) GetISCSITargets() ([]*ISCSITarget, error) {
+	return s.GetISCSITargetsContext(context.Background())
+}
+
+// GetISCSITargetsContext retrieves the list of all iSCSI targets.
+func (s *QnapSession) GetISCSITargetsContext(ctx context.Context) (_ []*ISCSITarget, err error) {
+	ctx, span := s.startSpan(ctx, "GetISCSITargets")
+	defer func() { endSpan(span, err) }()
+
+	if cached, ok := s.cache.getISCSITargets(); ok {
+		span.SetAttributes(attribute.Bool("qnap.cache_hit", true))
+		return cached, nil
+	}
+	generation := s.cache.start()
+
 	var result getISCSITargetsResponse
 
-	res, err := s.conn.NewRequest().
+	req := s.conn.NewRequest().
+		SetContext(ctx).
 		ExpectContentType("text/xml").
-		SetQueryParam("prod", "qts").
+		SetQueryParam("prod", s.Capabilities().ISCSIProduct).
 		SetQueryParam("proto", "iscsi").
-		SetQueryParam("target", "lio").
-		SetQueryParam("backend", "dm").
+		SetQueryParam("target", s.Capabilities().ISCSITarget).
+		SetQueryParam("backend", s.Capabilities().ISCSIBackend).
 		SetQueryParam("conf", "ini").
 		SetQueryParam("func", "extra_get").
 		SetQueryParam("targetList", "1").
-		SetResult(&result).
-		Post("cgi-bin/disk/iscsi_portal_setting.cgi")
+		SetResult(&result)
+
+	res, err := s.execute(req, resty.MethodPost, "cgi-bin/disk/iscsi_portal_setting.cgi")
 	if err != nil {
-		return nil, fmt.Errorf("failed to perform request: %v", err)
+		return nil, fmt.Errorf("failed to perform request: %w", err)
 	}
 	if res.StatusCode() != 200 {
 		return nil, fmt.Errorf("failed to perform request: unexpected HTTP status code: %v", res.StatusCode())
 	}
 	if result.AuthPassed != 1 {
-		return nil, fmt.Errorf("failed to perform request: authentication invalid: %v", string(res.Body()))
+		return nil, fmt.Errorf("failed to perform request: %w: %v", errAuthenticationInvalid, redact(string(res.Body())))
 	}
 	if result.Result != "0" {
 		return nil, fmt.Errorf("failed to perform request: unexpected result code: %v", result.Result)
 	}
 
+	s.cache.putISCSITargets(generation, result.ISCSITargetList.TargetInfo)
+
 	return result.ISCSITargetList.TargetInfo, nil
 }
+
+type createISCSITargetResponse struct {
+	AuthPassed  int    `xml:"authPassed"`
+	ISCSIModel  string `xml:"iSCSIModel"`
+	TargetIndex string `xml:"result"`
+}
+
+// CreateISCSITarget creates a new iSCSI target without authentication and returns it.
+// The IQN of the target is generated by the QNAP system from the name.
+func (s *QnapSession) CreateISCSITarget(name, alias string) (*ISCSITarget, error) {
+	return s.CreateISCSITargetContext(context.Background(), name, alias)
+}
+
+// CreateISCSITargetContext creates a new iSCSI target without authentication and returns it.
+// The IQN of the target is generated by the QNAP system from the name.
+func (s *QnapSession) CreateISCSITargetContext(ctx context.Context, name, alias string) (_ *ISCSITarget, err error) {
+	ctx, span := s.startSpan(ctx, "CreateISCSITarget", attribute.String("qnap.target_name", name))
+	defer func() { endSpan(span, err) }()
+
+	var result createISCSITargetResponse
+
+	if err := s.requireAdmin("create iSCSI target"); err != nil {
+		return nil, err
+	}
+
+	defer s.lockMutations()()
+
+	if alias == "" {
+		alias = name
+	}
+
+	req := s.conn.NewRequest().
+		SetContext(ctx).
+		ExpectContentType("text/xml").
+		SetQueryParam("prod", s.Capabilities().ISCSIProduct).
+		SetQueryParam("proto", "iscsi").
+		SetQueryParam("target", s.Capabilities().ISCSITarget).
+		SetQueryParam("backend", s.Capabilities().ISCSIBackend).
+		SetQueryParam("conf", "ini").
+		SetQueryParam("func", "add_target").
+		SetQueryParam("targetName", name).
+		SetQueryParam("targetAlias", alias).
+		SetQueryParam("bTargetDataDigest", "0").
+		SetQueryParam("bTargetHeaderDigest", "0").
+		SetQueryParam("bTargetClusterEnable", "1").
+		SetQueryParam("AuthMethod", "0").
+		SetResult(&result)
+
+	res, err := s.executeMutation(req, resty.MethodPost, "cgi-bin/disk/iscsi_target_setting.cgi")
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform request: %w", err)
+	}
+	if res.StatusCode() != 200 {
+		return nil, fmt.Errorf("failed to perform request: unexpected HTTP status code: %v", res.StatusCode())
+	}
+	if result.AuthPassed != 1 {
+		return nil, fmt.Errorf("failed to perform request: %w: %v", errAuthenticationInvalid, redact(string(res.Body())))
+	}
+
+	// the result contains the index of the new target, negative values are errors
+	targetIndex, err := strconv.Atoi(result.TargetIndex)
+	if err != nil || targetIndex < 0 {
+		return nil, fmt.Errorf("failed to perform request: unexpected result code: %v", result.TargetIndex)
+	}
+
+	span.SetAttributes(attribute.Int("qnap.target_index", targetIndex))
+
+	targets, err := s.GetISCSITargetsContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get iSCSI target %v: %w", targetIndex, err)
+	}
+
+	for _, target := range targets {
+		if target.TargetIndex == targetIndex {
+			return target, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to find iSCSI target %v", targetIndex)
+}
+
+// DeleteISCSITarget deletes an iSCSI target. The assigned LUNs are not deleted.
+func (s *QnapSession) DeleteISCSITarget(targetIndex int) error {
+	return s.DeleteISCSITargetContext(context.Background(), targetIndex)
+}
+
+// DeleteISCSITargetContext deletes an iSCSI target. The assigned LUNs are not deleted.
+func (s *QnapSession) DeleteISCSITargetContext(ctx context.Context, targetIndex int) (err error) {
+	ctx, span := s.startSpan(ctx, "DeleteISCSITarget", attribute.Int("qnap.target_index", targetIndex))
+	defer func() { endSpan(span, err) }()
+
+	var result genericResponse
+
+	if err := s.requireAdmin("delete iSCSI target"); err != nil {
+		return err
+	}
+
+	defer s.lockMutations()()
+
+	req := s.conn.NewRequest().
+		SetContext(ctx).
+		ExpectContentType("text/xml").
+		SetQueryParam("prod", s.Capabilities().ISCSIProduct).
+		SetQueryParam("proto", "iscsi").
+		SetQueryParam("target", s.Capabilities().ISCSITarget).
+		SetQueryParam("backend", s.Capabilities().ISCSIBackend).
+		SetQueryParam("conf", "ini").
+		SetQueryParam("func", "remove_target").
+		SetQueryParam("targetIndex", strconv.Itoa(targetIndex)).
+		SetResult(&result)
+
+	res, err := s.executeMutation(req, resty.MethodPost, "cgi-bin/disk/iscsi_target_setting.cgi")
+	if err != nil {
+		return fmt.Errorf("failed to perform request: %w", err)
+	}
+	if res.StatusCode() != 200 {
+		return fmt.Errorf("failed to perform request: unexpected HTTP status code: %v", res.StatusCode())
+	}
+	if result.AuthPassed != 1 {
+		return fmt.Errorf("failed to perform request: %w: %v", errAuthenticationInvalid, redact(string(res.Body())))
+	}
+	if result.Result != "0" {
+		return fmt.Errorf("failed to perform request: unexpected result code: %v", result.Result)
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package manager
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 	"testing"
@@ -84,6 +85,14 @@ func TestDisksRoundtrip(t *testing.T) {
 			}
 		})
 
+		// unassign the LUN
+		t.Run(fmt.Sprintf("Test_Storage Pool %v_UnassignFromTarget", pool.PoolID), func(t *testing.T) {
+			err = s.UnassignLUN(lun.LUNIndex, target.TargetIndex)
+			if err != nil {
+				t.Fatalf("Failed to unassign LUN '%v' from iSCSI target: %v", lunName, err)
+			}
+		})
+
 		// delete the lun
 		t.Run(fmt.Sprintf("Test_Storage Pool %v_DeleteLUN", pool.PoolID), func(t *testing.T) {
 			err := s.DeleteLUN(lun.LUNIndex)
@@ -93,3 +102,21 @@ func TestDisksRoundtrip(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateBlockBasedLUNEx_ZFSOptionsOnQTS(t *testing.T) {
+	s := &QnapSession{isAdmin: true, capabilities: lookupCapabilities("5.1.0")}
+
+	_, err := s.CreateBlockBasedLUNEx(1, "UnitTest_ZFS", 1, LUNAllocateMode_Thin, false, 99, &ZFSLUNOptions{Compression: true})
+	if err == nil {
+		t.Fatal("Error expected")
+	}
+}
+
+func TestDeleteLUN_PermissionDenied(t *testing.T) {
+	s := &QnapSession{username: "unittest-user", capabilities: defaultCapabilities}
+
+	err := s.DeleteLUN(1)
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("Permission error expected: %v", err)
+	}
+}
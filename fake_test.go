@@ -0,0 +1,54 @@
+package manager_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+	"github.com/nine-lives-later/go-qnap-disk-manager/qnapfake"
+)
+
+func connectFake(t *testing.T, fake *qnapfake.Server) *manager.QnapSession {
+	return connectFakeWithOptions(t, fake, &manager.ConfigOptions{APICallTimeout: 5 * time.Second})
+}
+
+func connectFakeWithOptions(t *testing.T, fake *qnapfake.Server, options *manager.ConfigOptions) *manager.QnapSession {
+	s, err := manager.Connect(fake.URL, "admin", "admin", options)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// concurrencyMeter measures the maximum number of concurrent requests, e.g. within an interceptor.
+type concurrencyMeter struct {
+	lock      sync.Mutex
+	active    int
+	maxActive int
+}
+
+// track counts the request as active while delaying it.
+func (m *concurrencyMeter) track(delay time.Duration) {
+	m.lock.Lock()
+	m.active++
+	if m.active > m.maxActive {
+		m.maxActive = m.active
+	}
+	m.lock.Unlock()
+
+	time.Sleep(delay)
+
+	m.lock.Lock()
+	m.active--
+	m.lock.Unlock()
+}
+
+// max returns the maximum number of concurrent requests.
+func (m *concurrencyMeter) max() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.maxActive
+}
@@ -0,0 +1,272 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LUNCollectorOptions contains the settings of the orphaned LUN collector.
+type LUNCollectorOptions struct {
+	Patterns    []string      // shell patterns of the LUN names to collect, e.g. "UnitTest_*" for a prefix; at least one is required
+	GracePeriod time.Duration // time a LUN has to be orphaned before being collected, zero to report it immediately; required to delete
+	Delete      bool          // delete the collected LUNs, otherwise they are only reported (dry-run)
+
+	// StateFile persists the time each LUN has been seen orphaned for the first time, so the grace period
+	// spans several processes, e.g. one-shot runs of a cron job. Optional, the times are kept in memory only by default.
+	StateFile string
+}
+
+// OrphanedLUN is a LUN matching the patterns, which is not assigned to any iSCSI target.
+type OrphanedLUN struct {
+	LUN     *LUN
+	Since   time.Time // first time the LUN has been seen orphaned by the collector
+	Deleted bool
+}
+
+// LUNCollectResult contains the orphaned LUNs found by a collection.
+type LUNCollectResult struct {
+	Collected []*OrphanedLUN // orphaned for longer than the grace period, deleted unless dry-run or failed
+	Pending   []*OrphanedLUN // orphaned, but still within the grace period
+}
+
+// LUNCollector finds LUNs left behind, e.g. by failed test runs or crashed provisioners,
+// and optionally deletes them.
+//
+// The QNAP system does not report the creation time of LUNs, so the grace period starts
+// when the collector sees a LUN orphaned for the first time. Collect() has to be called
+// repeatedly, e.g. periodically, on the same collector or on collectors sharing the same
+// LUNCollectorOptions.StateFile.
+type LUNCollector struct {
+	client  Client
+	options LUNCollectorOptions
+
+	lock      sync.Mutex
+	firstSeen map[orphanedLUNKey]time.Time
+}
+
+// the index of deleted LUNs is reused, so the name is part of the key
+type orphanedLUNKey struct {
+	index int
+	name  string
+}
+
+// lunCollectorState is the content of the state file.
+type lunCollectorState struct {
+	LUNs []lunCollectorStateEntry `json:"luns"`
+}
+
+type lunCollectorStateEntry struct {
+	Index int       `json:"index"`
+	Name  string    `json:"name"`
+	Since time.Time `json:"since"`
+}
+
+// NewLUNCollector creates a new collector of orphaned LUNs.
+func NewLUNCollector(client Client, options *LUNCollectorOptions) (*LUNCollector, error) {
+	if options == nil || len(options.Patterns) == 0 {
+		return nil, fmt.Errorf("at least one LUN name pattern is required")
+	}
+	for _, pattern := range options.Patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid LUN name pattern '%v': %w", pattern, err)
+		}
+	}
+	if options.GracePeriod < 0 {
+		return nil, fmt.Errorf("grace period must not be negative")
+	}
+	if options.Delete && options.GracePeriod == 0 {
+		return nil, fmt.Errorf("grace period is required to delete LUNs")
+	}
+
+	c := &LUNCollector{
+		client:    client,
+		options:   *options,
+		firstSeen: map[orphanedLUNKey]time.Time{},
+	}
+
+	if err := c.loadState(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// loadState reads the first-seen times of the state file, if any. A missing file is no error.
+func (c *LUNCollector) loadState() error {
+	if c.options.StateFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.options.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read LUN collector state: %w", err)
+	}
+
+	var state lunCollectorState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse LUN collector state '%v': %w", c.options.StateFile, err)
+	}
+
+	for _, entry := range state.LUNs {
+		c.firstSeen[orphanedLUNKey{index: entry.Index, name: entry.Name}] = entry.Since
+	}
+
+	return nil
+}
+
+// saveState writes the first-seen times to the state file, if any.
+// The file is replaced atomically, so an interrupted run never leaves a corrupt state behind.
+func (c *LUNCollector) saveState() error {
+	if c.options.StateFile == "" {
+		return nil
+	}
+
+	state := lunCollectorState{LUNs: make([]lunCollectorStateEntry, 0, len(c.firstSeen))}
+	for key, since := range c.firstSeen {
+		state.LUNs = append(state.LUNs, lunCollectorStateEntry{Index: key.index, Name: key.name, Since: since})
+	}
+	sort.Slice(state.LUNs, func(i, j int) bool { return state.LUNs[i].Index < state.LUNs[j].Index })
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to write LUN collector state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.options.StateFile), filepath.Base(c.options.StateFile)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write LUN collector state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write LUN collector state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write LUN collector state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.options.StateFile); err != nil {
+		return fmt.Errorf("failed to write LUN collector state: %w", err)
+	}
+
+	return nil
+}
+
+// Collect finds the orphaned LUNs and deletes the ones orphaned for longer than the grace period,
+// unless running as dry-run. Each LUN is read again right before its deletion and skipped, if it has
+// been assigned or replaced meanwhile. Failed deletions and a failed write of the state file are returned as
+// joined error, together with the result.
+func (c *LUNCollector) Collect() (*LUNCollectResult, error) {
+	return c.CollectContext(context.Background())
+}
+
+// CollectContext finds the orphaned LUNs and deletes the ones orphaned for longer than the grace period,
+// unless running as dry-run. Each LUN is read again right before its deletion and skipped, if it has
+// been assigned or replaced meanwhile. Failed deletions and a failed write of the state file are returned as
+// joined error, together with the result.
+func (c *LUNCollector) CollectContext(ctx context.Context) (*LUNCollectResult, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	luns, err := c.client.GetLUNsContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve LUNs: %w", err)
+	}
+
+	now := time.Now()
+	result := &LUNCollectResult{}
+	seen := make(map[orphanedLUNKey]time.Time, len(c.firstSeen))
+
+	for _, lun := range luns {
+		if !c.orphaned(lun) {
+			continue
+		}
+
+		key := orphanedLUNKey{index: lun.LUNIndex, name: lun.LUNName}
+
+		since, ok := c.firstSeen[key]
+		if !ok {
+			since = now
+		}
+		seen[key] = since
+
+		orphan := &OrphanedLUN{LUN: lun, Since: since}
+
+		if now.Sub(since) < c.options.GracePeriod {
+			result.Pending = append(result.Pending, orphan)
+		} else {
+			result.Collected = append(result.Collected, orphan)
+		}
+	}
+
+	// forget the LUNs which are gone or in use again
+	c.firstSeen = seen
+
+	if !c.options.Delete {
+		return result, c.saveState()
+	}
+
+	var errs []error
+
+	collected := result.Collected[:0]
+
+	for _, orphan := range result.Collected {
+		key := orphanedLUNKey{index: orphan.LUN.LUNIndex, name: orphan.LUN.LUNName}
+
+		// the LUN may have been assigned or replaced since the listing
+		lun, err := c.client.GetLUNByIndexContext(ctx, orphan.LUN.LUNIndex)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to retrieve LUN '%v' (#%v): %w", orphan.LUN.LUNName, orphan.LUN.LUNIndex, err))
+			collected = append(collected, orphan)
+			continue
+		}
+		if lun == nil || lun.LUNName != orphan.LUN.LUNName || !c.orphaned(lun) {
+			delete(c.firstSeen, key)
+			continue
+		}
+
+		collected = append(collected, orphan)
+
+		if err := c.client.DeleteLUNContext(ctx, orphan.LUN.LUNIndex); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete LUN '%v' (#%v): %w", orphan.LUN.LUNName, orphan.LUN.LUNIndex, err))
+			continue
+		}
+
+		orphan.Deleted = true
+		delete(c.firstSeen, key)
+	}
+
+	result.Collected = collected
+
+	if err := c.saveState(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// orphaned returns true if the LUN matches the patterns and is not assigned to any iSCSI target.
+func (c *LUNCollector) orphaned(lun *LUN) bool {
+	if lun.LUNTargetList.SingleRow != nil || lun.IsSnap != 0 || lun.IsRemoving != 0 {
+		return false
+	}
+
+	for _, pattern := range c.options.Patterns {
+		if ok, _ := path.Match(pattern, lun.LUNName); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,206 @@
+package manager_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+	"github.com/nine-lives-later/go-qnap-disk-manager/qnapfake"
+	"github.com/nine-lives-later/go-qnap-disk-manager/qnapmock"
+)
+
+func TestLUNCollector(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	s := connectFake(t, fake)
+
+	target, err := s.CreateISCSITarget("k8s", "")
+	if err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+	for _, name := range []string{"UnitTest_1", "UnitTest_2", "data01"} {
+		if _, err := s.CreateBlockBasedLUN(1, name, 1, manager.LUNAllocateMode_Thin, false, 80); err != nil {
+			t.Fatalf("Failed to create LUN: %v", err)
+		}
+	}
+	assigned := fake.LUNs()[1]
+	if err := s.AssignLUN(assigned.LUNIndex, target.TargetIndex); err != nil {
+		t.Fatalf("Failed to assign LUN: %v", err)
+	}
+
+	if _, err := manager.NewLUNCollector(s, &manager.LUNCollectorOptions{}); err == nil {
+		t.Fatal("Error expected without patterns")
+	}
+
+	// dry-run, respecting the grace period
+	gc, err := manager.NewLUNCollector(s, &manager.LUNCollectorOptions{Patterns: []string{"UnitTest_*"}, GracePeriod: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	result, err := gc.Collect()
+	if err != nil {
+		t.Fatalf("Failed to collect: %v", err)
+	}
+	if len(result.Collected) != 0 || len(result.Pending) != 1 || result.Pending[0].LUN.LUNName != "UnitTest_1" {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	result, err = gc.Collect()
+	if err != nil {
+		t.Fatalf("Failed to collect: %v", err)
+	}
+	if len(result.Collected) != 1 || result.Collected[0].Deleted || len(fake.LUNs()) != 3 {
+		t.Fatalf("Dry-run must not delete: %+v", result)
+	}
+
+	// delete
+	if _, err := manager.NewLUNCollector(s, &manager.LUNCollectorOptions{Patterns: []string{"UnitTest_*"}, Delete: true}); err == nil {
+		t.Fatal("Error expected without grace period")
+	}
+
+	gc, err = manager.NewLUNCollector(s, &manager.LUNCollectorOptions{Patterns: []string{"UnitTest_*"}, GracePeriod: 50 * time.Millisecond, Delete: true})
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	if _, err := gc.Collect(); err != nil {
+		t.Fatalf("Failed to collect: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	result, err = gc.Collect()
+	if err != nil {
+		t.Fatalf("Failed to collect: %v", err)
+	}
+	if len(result.Collected) != 1 || !result.Collected[0].Deleted {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+
+	luns := fake.LUNs()
+	if len(luns) != 2 || luns[0].LUNName != "UnitTest_2" || luns[1].LUNName != "data01" {
+		t.Fatalf("Wrong remaining LUNs: %+v", luns)
+	}
+}
+
+func TestLUNCollector_RecheckBeforeDelete(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	s := connectFake(t, fake)
+
+	target, err := s.CreateISCSITarget("k8s", "")
+	if err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+	for _, name := range []string{"UnitTest_1", "UnitTest_2"} {
+		if _, err := s.CreateBlockBasedLUN(1, name, 1, manager.LUNAllocateMode_Thin, false, 80); err != nil {
+			t.Fatalf("Failed to create LUN: %v", err)
+		}
+	}
+
+	// the listing is outdated: a LUN is assigned meanwhile, one is gone and one has been replaced
+	listing := append(fake.LUNs(), &manager.LUN{LUNIndex: 42, LUNName: "UnitTest_gone"}, &manager.LUN{LUNIndex: 43, LUNName: "UnitTest_old"})
+
+	if err := s.AssignLUN(listing[1].LUNIndex, target.TargetIndex); err != nil {
+		t.Fatalf("Failed to assign LUN: %v", err)
+	}
+
+	client := &qnapmock.Client{
+		GetLUNsFunc: func() ([]*manager.LUN, error) {
+			return listing, nil
+		},
+		GetLUNByIndexFunc: func(lunIndex int) (*manager.LUN, error) {
+			if lunIndex == 43 {
+				return &manager.LUN{LUNIndex: 43, LUNName: "pvc-new"}, nil
+			}
+			return s.GetLUNByIndex(lunIndex)
+		},
+		DeleteLUNFunc: s.DeleteLUN,
+	}
+
+	gc, err := manager.NewLUNCollector(client, &manager.LUNCollectorOptions{Patterns: []string{"UnitTest_*"}, GracePeriod: 50 * time.Millisecond, Delete: true})
+	if err != nil {
+		t.Fatalf("Failed to create collector: %v", err)
+	}
+
+	if _, err := gc.Collect(); err != nil {
+		t.Fatalf("Failed to collect: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	result, err := gc.Collect()
+	if err != nil {
+		t.Fatalf("Failed to collect: %v", err)
+	}
+	if len(result.Collected) != 1 || result.Collected[0].LUN.LUNName != "UnitTest_1" || !result.Collected[0].Deleted {
+		t.Fatalf("Only the orphaned LUN is expected to be collected: %+v", result)
+	}
+
+	if calls := client.CallsOf("DeleteLUNContext"); len(calls) != 1 || calls[0].Args[1] != listing[0].LUNIndex {
+		t.Fatalf("Wrong deletions: %+v", calls)
+	}
+	if luns := fake.LUNs(); len(luns) != 1 || luns[0].LUNName != "UnitTest_2" {
+		t.Fatalf("Wrong remaining LUNs: %+v", luns)
+	}
+}
+
+func TestLUNCollector_StateFile(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	s := connectFake(t, fake)
+
+	if _, err := s.CreateBlockBasedLUN(1, "UnitTest_1", 1, manager.LUNAllocateMode_Thin, false, 80); err != nil {
+		t.Fatalf("Failed to create LUN: %v", err)
+	}
+
+	options := &manager.LUNCollectorOptions{
+		Patterns:    []string{"UnitTest_*"},
+		GracePeriod: 50 * time.Millisecond,
+		Delete:      true,
+		StateFile:   filepath.Join(t.TempDir(), "gc-state.json"),
+	}
+
+	// one-shot runs, each with a new collector
+	collect := func() *manager.LUNCollectResult {
+		gc, err := manager.NewLUNCollector(s, options)
+		if err != nil {
+			t.Fatalf("Failed to create collector: %v", err)
+		}
+
+		result, err := gc.Collect()
+		if err != nil {
+			t.Fatalf("Failed to collect: %v", err)
+		}
+		return result
+	}
+
+	if result := collect(); len(result.Pending) != 1 || len(result.Collected) != 0 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if result := collect(); len(result.Collected) != 1 || !result.Collected[0].Deleted {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	if len(fake.LUNs()) != 0 {
+		t.Fatal("LUN not deleted")
+	}
+
+	// corrupt state
+	if err := os.WriteFile(options.StateFile, []byte("{"), 0o600); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+	if _, err := manager.NewLUNCollector(s, options); err == nil {
+		t.Fatal("Error expected for corrupt state file")
+	}
+}
@@ -0,0 +1,414 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ISCSIConfigVersion is the version of the exported iSCSI configuration documents.
+const ISCSIConfigVersion = 1
+
+// ErrNameConflict is returned by ImportISCSIConfig when a target or LUN name already exists on the QNAP system.
+var ErrNameConflict = errors.New("name already exists")
+
+// ISCSIConfig is the exported iSCSI configuration of a QNAP system, see ExportISCSIConfig.
+// It is meant to be stored as JSON and restored on another system with ImportISCSIConfig.
+type ISCSIConfig struct {
+	Version    int                 `json:"version"`
+	ExportedAt time.Time           `json:"exported_at"`
+	Source     string              `json:"source"` // host the configuration has been exported from
+	Targets    []ISCSIConfigTarget `json:"targets"`
+	LUNs       []ISCSIConfigLUN    `json:"luns"`
+}
+
+// ISCSIConfigTarget is an exported iSCSI target.
+type ISCSIConfigTarget struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+	Alias string `json:"alias"`
+	IQN   string `json:"iqn"`
+}
+
+// ISCSIConfigLUN is an exported LUN, including its target mapping and initiator ACLs.
+type ISCSIConfigLUN struct {
+	Index            int                    `json:"index"`
+	Name             string                 `json:"name"`
+	PoolID           int                    `json:"pool_id"`
+	CapacityBytes    int64                  `json:"capacity_bytes"`
+	ThinAllocate     bool                   `json:"thin_allocate"`
+	SSDCache         bool                   `json:"ssd_cache"`
+	ThresholdPercent int                    `json:"threshold_percent"`
+	SectorSize       int                    `json:"sector_size"`
+	NAA              string                 `json:"naa"`
+	Mapping          *ISCSIConfigMapping    `json:"mapping,omitempty"`
+	Initiators       []ISCSIConfigInitiator `json:"initiators,omitempty"`
+}
+
+// ISCSIConfigMapping is the assignment of an exported LUN to an iSCSI target.
+type ISCSIConfigMapping struct {
+	TargetIndex int  `json:"target_index"`
+	LUNNumber   int  `json:"lun_number"`
+	Enabled     bool `json:"enabled"`
+}
+
+// ISCSIConfigInitiator is an initiator ACL entry of an exported LUN.
+type ISCSIConfigInitiator struct {
+	Index      int    `json:"index"`
+	IQN        string `json:"iqn"`
+	AccessMode int    `json:"access_mode"`
+}
+
+// ParseISCSIConfig parses an exported iSCSI configuration document and checks its version.
+func ParseISCSIConfig(data []byte) (*ISCSIConfig, error) {
+	var config ISCSIConfig
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse iSCSI configuration: %w", err)
+	}
+	if config.Version != ISCSIConfigVersion {
+		return nil, fmt.Errorf("unsupported iSCSI configuration version: %v (expected %v)", config.Version, ISCSIConfigVersion)
+	}
+
+	return &config, nil
+}
+
+// ExportISCSIConfig exports all iSCSI targets and LUNs, including their mappings and initiator ACLs.
+func (s *QnapSession) ExportISCSIConfig() (*ISCSIConfig, error) {
+	return s.ExportISCSIConfigContext(context.Background())
+}
+
+// ExportISCSIConfigContext exports all iSCSI targets and LUNs, including their mappings and initiator ACLs.
+func (s *QnapSession) ExportISCSIConfigContext(ctx context.Context) (_ *ISCSIConfig, err error) {
+	ctx, span := s.startSpan(ctx, "ExportISCSIConfig")
+	defer func() { endSpan(span, err) }()
+
+	targets, err := s.GetISCSITargetsContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve iSCSI targets: %w", err)
+	}
+	luns, err := s.GetLUNsContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve LUNs: %w", err)
+	}
+
+	config := &ISCSIConfig{
+		Version:    ISCSIConfigVersion,
+		ExportedAt: time.Now().UTC(),
+		Source:     s.host,
+		Targets:    make([]ISCSIConfigTarget, 0, len(targets)),
+		LUNs:       make([]ISCSIConfigLUN, 0, len(luns)),
+	}
+
+	for _, target := range targets {
+		config.Targets = append(config.Targets, ISCSIConfigTarget{
+			Index: target.TargetIndex,
+			Name:  target.TargetName,
+			Alias: target.TargetAlias,
+			IQN:   target.TargetIQN,
+		})
+	}
+
+	for _, lun := range luns {
+		l := ISCSIConfigLUN{
+			Index:            lun.LUNIndex,
+			Name:             lun.LUNName,
+			PoolID:           lun.PoolID,
+			CapacityBytes:    lun.CapacityBytes,
+			ThinAllocate:     lun.LUNThinAllocate,
+			SSDCache:         lun.SsdCache == "yes" || lun.SsdCache == "1",
+			ThresholdPercent: lun.LUNThresholdPercent,
+			SectorSize:       lun.LUNSectorSize,
+			NAA:              lun.LUNNAA,
+		}
+
+		if row := lun.LUNTargetList.SingleRow; row != nil {
+			l.Mapping = &ISCSIConfigMapping{
+				TargetIndex: row.TargetIndex,
+				LUNNumber:   row.LUNNumber,
+				Enabled:     row.LUNEnable,
+			}
+		}
+
+		for _, initiator := range lun.LUNInitiatorList.LUNInitInfo {
+			l.Initiators = append(l.Initiators, ISCSIConfigInitiator{
+				Index:      initiator.InitiatorIndex,
+				IQN:        initiator.InitiatorIQN,
+				AccessMode: initiator.AccessMode,
+			})
+		}
+
+		config.LUNs = append(config.LUNs, l)
+	}
+
+	return config, nil
+}
+
+type ConflictStrategy string
+
+const (
+	ConflictStrategy_Fail   ConflictStrategy = "fail"   // abort the import before any change
+	ConflictStrategy_Skip   ConflictStrategy = "skip"   // keep the existing target or LUN, and use it for the mappings
+	ConflictStrategy_Rename ConflictStrategy = "rename" // import with a numbered suffix, e.g. data-2
+)
+
+// ImportOptions contains the settings of ImportISCSIConfig.
+type ImportOptions struct {
+	DryRun      bool             // only report the actions, without changing anything
+	Conflict    ConflictStrategy // handling of existing names, defaults to ConflictStrategy_Fail
+	PoolMapping map[int]int      // maps the exported storage pool IDs to the ones of the QNAP system, unmapped IDs are kept
+
+	// SkipInitiators imports the LUNs without their initiator ACLs, which then have to be restored manually.
+	SkipInitiators bool
+}
+
+type ImportActionKind string
+
+const (
+	ImportActionKind_CreateTarget ImportActionKind = "create_target"
+	ImportActionKind_CreateLUN    ImportActionKind = "create_lun"
+	ImportActionKind_AssignLUN    ImportActionKind = "assign_lun"
+	ImportActionKind_AddInitiator ImportActionKind = "add_initiator"
+	ImportActionKind_Skip         ImportActionKind = "skip"
+)
+
+// ImportAction is a single change of an import.
+type ImportAction struct {
+	Kind   ImportActionKind
+	Name   string // name of the target or LUN on the QNAP system
+	Detail string
+}
+
+// ImportResult contains the actions of an import, performed or planned on dry-run.
+type ImportResult struct {
+	Actions  []ImportAction
+	Warnings []string    // parts of the configuration which are not restored, e.g. skipped initiator ACLs
+	Targets  map[int]int // maps the exported target indexes to the ones of the QNAP system, -1 on dry-run
+	LUNs     map[int]int // maps the exported LUN indexes to the ones of the QNAP system, -1 on dry-run
+}
+
+// ImportISCSIConfig recreates the exported iSCSI targets, LUNs, mappings and initiator ACLs.
+// The names are checked for conflicts before any change is made.
+func (s *QnapSession) ImportISCSIConfig(config *ISCSIConfig, options *ImportOptions) (*ImportResult, error) {
+	return s.ImportISCSIConfigContext(context.Background(), config, options)
+}
+
+// ImportISCSIConfigContext recreates the exported iSCSI targets, LUNs, mappings and initiator ACLs.
+// The names are checked for conflicts before any change is made.
+func (s *QnapSession) ImportISCSIConfigContext(ctx context.Context, config *ISCSIConfig, options *ImportOptions) (_ *ImportResult, err error) {
+	ctx, span := s.startSpan(ctx, "ImportISCSIConfig")
+	defer func() { endSpan(span, err) }()
+
+	if options == nil {
+		options = &ImportOptions{}
+	}
+	if config == nil {
+		return nil, fmt.Errorf("no iSCSI configuration provided")
+	}
+	if config.Version != ISCSIConfigVersion {
+		return nil, fmt.Errorf("unsupported iSCSI configuration version: %v (expected %v)", config.Version, ISCSIConfigVersion)
+	}
+
+	for _, lun := range config.LUNs {
+		if lun.CapacityBytes <= 0 {
+			return nil, fmt.Errorf("failed to import LUN %v: invalid capacity: %v bytes", lun.Name, lun.CapacityBytes)
+		}
+		for _, initiator := range lun.Initiators {
+			if initiator.IQN == "" {
+				return nil, fmt.Errorf("failed to import LUN %v: initiator ACL entry %v without IQN", lun.Name, initiator.Index)
+			}
+		}
+	}
+
+	conflict := options.Conflict
+	switch conflict {
+	case "":
+		conflict = ConflictStrategy_Fail
+	case ConflictStrategy_Fail, ConflictStrategy_Skip, ConflictStrategy_Rename:
+	default:
+		return nil, fmt.Errorf("unknown conflict strategy: %v", conflict)
+	}
+
+	if !options.DryRun {
+		if err := s.requireAdmin("import iSCSI configuration"); err != nil {
+			return nil, err
+		}
+	}
+
+	// existing names
+	targets, err := s.GetISCSITargetsContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve iSCSI targets: %w", err)
+	}
+	luns, err := s.GetLUNsContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve LUNs: %w", err)
+	}
+
+	targetNames := map[string]int{}
+	for _, target := range targets {
+		targetNames[target.TargetName] = target.TargetIndex
+	}
+	lunNames := map[string]*LUN{}
+	for _, lun := range luns {
+		lunNames[lun.LUNName] = lun
+	}
+
+	result := &ImportResult{
+		Targets: map[int]int{},
+		LUNs:    map[int]int{},
+	}
+
+	// resolve the name conflicts before changing anything
+	targetPlan := make([]string, len(config.Targets)) // new names, empty if skipped
+	importedTargetNames := map[int]string{}
+	for i, target := range config.Targets {
+		index, exists := targetNames[target.Name]
+		importedTargetNames[target.Index] = target.Name
+
+		switch {
+		case !exists:
+			targetPlan[i] = target.Name
+			targetNames[target.Name] = -1
+		case conflict == ConflictStrategy_Fail:
+			return nil, fmt.Errorf("failed to import iSCSI target %v: %w", target.Name, ErrNameConflict)
+		case conflict == ConflictStrategy_Skip:
+			result.Targets[target.Index] = index
+			result.Actions = append(result.Actions, ImportAction{Kind: ImportActionKind_Skip, Name: target.Name, Detail: "iSCSI target already exists"})
+		default:
+			name := uniqueName(target.Name, func(name string) bool { _, ok := targetNames[name]; return ok })
+			targetPlan[i] = name
+			targetNames[name] = -1
+			importedTargetNames[target.Index] = name
+		}
+	}
+
+	lunPlan := make([]string, len(config.LUNs)) // new names, empty if skipped
+	for i, lun := range config.LUNs {
+		existing, exists := lunNames[lun.Name]
+
+		switch {
+		case !exists:
+			lunPlan[i] = lun.Name
+			lunNames[lun.Name] = nil
+		case conflict == ConflictStrategy_Fail:
+			return nil, fmt.Errorf("failed to import LUN %v: %w", lun.Name, ErrNameConflict)
+		case conflict == ConflictStrategy_Skip:
+			if existing != nil {
+				result.LUNs[lun.Index] = existing.LUNIndex
+			}
+			result.Actions = append(result.Actions, ImportAction{Kind: ImportActionKind_Skip, Name: lun.Name, Detail: "LUN already exists"})
+		default:
+			name := uniqueName(lun.Name, func(name string) bool { _, ok := lunNames[name]; return ok })
+			lunPlan[i] = name
+			lunNames[name] = nil
+		}
+	}
+
+	// create the targets
+	for i, target := range config.Targets {
+		name := targetPlan[i]
+		if name == "" {
+			continue
+		}
+
+		result.Actions = append(result.Actions, ImportAction{Kind: ImportActionKind_CreateTarget, Name: name, Detail: fmt.Sprintf("exported as %v", target.IQN)})
+
+		if options.DryRun {
+			result.Targets[target.Index] = -1
+			continue
+		}
+
+		created, err := s.CreateISCSITargetContext(ctx, name, target.Alias)
+		if err != nil {
+			return result, fmt.Errorf("failed to import iSCSI target %v: %w", name, err)
+		}
+		result.Targets[target.Index] = created.TargetIndex
+	}
+
+	// create the LUNs and their mappings
+	for i, lun := range config.LUNs {
+		name := lunPlan[i]
+		if name == "" {
+			continue
+		}
+
+		poolID := lun.PoolID
+		if mapped, ok := options.PoolMapping[poolID]; ok {
+			poolID = mapped
+		}
+
+		// never create LUNs smaller than exported
+		capacityGB := int((lun.CapacityBytes + 1<<30 - 1) >> 30)
+		allocateMode := LUNAllocateMode_Thick
+		if lun.ThinAllocate {
+			allocateMode = LUNAllocateMode_Thin
+		}
+
+		result.Actions = append(result.Actions, ImportAction{Kind: ImportActionKind_CreateLUN, Name: name, Detail: fmt.Sprintf("pool %v, %v GB", poolID, capacityGB)})
+
+		lunIndex := -1
+
+		if !options.DryRun {
+			created, err := s.CreateBlockBasedLUNContext(ctx, poolID, name, capacityGB, allocateMode, lun.SSDCache, lun.ThresholdPercent, nil)
+			if err != nil {
+				return result, fmt.Errorf("failed to import LUN %v: %w", name, err)
+			}
+			lunIndex = created.LUNIndex
+		}
+		result.LUNs[lun.Index] = lunIndex
+
+		targetIndex := -1
+		if lun.Mapping != nil {
+			index, ok := result.Targets[lun.Mapping.TargetIndex]
+			if ok {
+				targetIndex = index
+				result.Actions = append(result.Actions, ImportAction{Kind: ImportActionKind_AssignLUN, Name: name, Detail: "to target " + importedTargetNames[lun.Mapping.TargetIndex]})
+			} else {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("LUN %v: target %v of the mapping is not part of the configuration", name, lun.Mapping.TargetIndex))
+			}
+		}
+
+		initiators := lun.Initiators
+		if options.SkipInitiators && len(initiators) > 0 {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("LUN %v: %v initiator ACL entries skipped, they have to be restored manually", name, len(initiators)))
+			initiators = nil
+		}
+		for _, initiator := range initiators {
+			result.Actions = append(result.Actions, ImportAction{Kind: ImportActionKind_AddInitiator, Name: name, Detail: initiator.IQN})
+		}
+
+		if options.DryRun || (targetIndex < 0 && len(initiators) == 0) {
+			continue
+		}
+
+		// the volume has to be ready before assigning the LUN or changing its ACL
+		if _, err := s.WaitForLUNVolumeContext(ctx, lunIndex); err != nil {
+			return result, fmt.Errorf("failed to import LUN %v: %w", name, err)
+		}
+		if targetIndex >= 0 {
+			if err := s.AssignLUNContext(ctx, lunIndex, targetIndex); err != nil {
+				return result, fmt.Errorf("failed to import mapping of LUN %v: %w", name, err)
+			}
+		}
+		for _, initiator := range initiators {
+			if err := s.AddLUNInitiatorContext(ctx, lunIndex, initiator.IQN, initiator.AccessMode); err != nil {
+				return result, fmt.Errorf("failed to import initiator ACL of LUN %v: %w", name, err)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// uniqueName returns the name with the lowest numbered suffix not in use, e.g. data-2.
+func uniqueName(name string, exists func(name string) bool) string {
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%v-%v", name, i)
+		if !exists(candidate) {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package manager_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+	"github.com/nine-lives-later/go-qnap-disk-manager/qnapfake"
+)
+
+func TestISCSIConfig_ExportImport(t *testing.T) {
+	source := qnapfake.NewServer("admin", "admin")
+	defer source.Close()
+	destination := qnapfake.NewServer("admin", "admin")
+	defer destination.Close()
+
+	src := connectFake(t, source)
+	dst := connectFake(t, destination)
+
+	target, err := src.CreateISCSITarget("k8s", "")
+	if err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+	lun, err := src.CreateBlockBasedLUN(1, "data01", 10, manager.LUNAllocateMode_Thick, false, 90)
+	if err != nil {
+		t.Fatalf("Failed to create LUN: %v", err)
+	}
+	if err := src.AssignLUN(lun.LUNIndex, target.TargetIndex); err != nil {
+		t.Fatalf("Failed to assign LUN: %v", err)
+	}
+
+	// export as JSON document
+	config, err := src.ExportISCSIConfig()
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	doc, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	config, err = manager.ParseISCSIConfig(doc)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if len(config.Targets) != 1 || len(config.LUNs) != 1 || config.LUNs[0].Mapping == nil {
+		t.Fatalf("Wrong export: %s", doc)
+	}
+
+	// dry-run
+	result, err := dst.ImportISCSIConfig(config, &manager.ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Failed to import (dry-run): %v", err)
+	}
+	if len(result.Actions) != 3 || len(destination.LUNs()) != 0 || len(destination.ISCSITargets()) != 0 {
+		t.Fatalf("Dry-run changed the system or has wrong actions: %+v", result.Actions)
+	}
+
+	// import
+	if _, err := dst.ImportISCSIConfig(config, nil); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+
+	luns := destination.LUNs()
+	if len(luns) != 1 || luns[0].LUNName != "data01" || luns[0].LUNThinAllocate || luns[0].LUNThresholdPercent != 90 || luns[0].LUNTargetList.SingleRow == nil {
+		t.Fatalf("Wrong imported LUNs: %+v", luns)
+	}
+
+	// conflicts
+	if _, err := dst.ImportISCSIConfig(config, nil); !errors.Is(err, manager.ErrNameConflict) {
+		t.Fatalf("ErrNameConflict expected: %v", err)
+	}
+
+	result, err = dst.ImportISCSIConfig(config, &manager.ImportOptions{Conflict: manager.ConflictStrategy_Skip})
+	if err != nil {
+		t.Fatalf("Failed to import (skip): %v", err)
+	}
+	if len(result.Actions) != 2 || result.Actions[0].Kind != manager.ImportActionKind_Skip || len(destination.LUNs()) != 1 {
+		t.Fatalf("Wrong actions (skip): %+v", result.Actions)
+	}
+
+	result, err = dst.ImportISCSIConfig(config, &manager.ImportOptions{Conflict: manager.ConflictStrategy_Rename, DryRun: true})
+	if err != nil {
+		t.Fatalf("Failed to import (rename): %v", err)
+	}
+	if len(result.Actions) != 3 || result.Actions[0].Name != "k8s-2" || result.Actions[1].Name != "data01-2" {
+		t.Fatalf("Wrong actions (rename): %+v", result.Actions)
+	}
+}
+
+func TestISCSIConfig_ImportInvalid(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	s := connectFake(t, fake)
+
+	if _, err := s.ImportISCSIConfig(nil, nil); err == nil {
+		t.Fatal("Error expected for missing configuration")
+	}
+
+	config := &manager.ISCSIConfig{
+		Version: manager.ISCSIConfigVersion,
+		LUNs:    []manager.ISCSIConfigLUN{{Index: 1, Name: "empty", PoolID: 1}},
+	}
+	if _, err := s.ImportISCSIConfig(config, &manager.ImportOptions{DryRun: true}); err == nil {
+		t.Fatal("Error expected for LUN without capacity")
+	}
+
+	config.LUNs[0].CapacityBytes = 1 << 30
+	config.LUNs[0].Initiators = []manager.ISCSIConfigInitiator{{Index: 0}}
+	if _, err := s.ImportISCSIConfig(config, &manager.ImportOptions{DryRun: true}); err == nil {
+		t.Fatal("Error expected for initiator ACL without IQN")
+	}
+}
+
+func TestISCSIConfig_ImportInitiatorsAndCapacity(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	s := connectFake(t, fake)
+
+	config := &manager.ISCSIConfig{
+		Version: manager.ISCSIConfigVersion,
+		LUNs: []manager.ISCSIConfigLUN{{
+			Index:         1,
+			Name:          "data01",
+			PoolID:        1,
+			CapacityBytes: 10<<30 + 1, // rounded up
+			Initiators:    []manager.ISCSIConfigInitiator{{Index: 0, IQN: "iqn.1993-08.org.debian:01:node1", AccessMode: 1}},
+		}},
+	}
+
+	result, err := s.ImportISCSIConfig(config, &manager.ImportOptions{DryRun: true, SkipInitiators: true})
+	if err != nil {
+		t.Fatalf("Failed to import (dry-run): %v", err)
+	}
+	if len(result.Warnings) != 1 || len(result.Actions) != 1 || result.Actions[0].Detail != "pool 1, 11 GB" {
+		t.Fatalf("Wrong result: %+v", result)
+	}
+
+	result, err = s.ImportISCSIConfig(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if len(result.Warnings) != 0 || len(result.Actions) != 2 || result.Actions[1].Kind != manager.ImportActionKind_AddInitiator {
+		t.Fatalf("Wrong result: %+v", result)
+	}
+
+	luns := fake.LUNs()
+	if len(luns) != 1 || len(luns[0].LUNInitiatorList.LUNInitInfo) != 1 {
+		t.Fatalf("Initiator ACL not restored: %+v", luns)
+	}
+	if initiator := luns[0].LUNInitiatorList.LUNInitInfo[0]; initiator.InitiatorIQN != "iqn.1993-08.org.debian:01:node1" || initiator.AccessMode != 1 {
+		t.Fatalf("Wrong initiator ACL: %+v", initiator)
+	}
+}
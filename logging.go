@@ -0,0 +1,69 @@
+package manager
+
+import (
+	"context"
+	"encoding/xml"
+	"log/slog"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+type resultCheckResponse struct {
+	AuthPassed string `xml:"authPassed"`
+	Result     string `xml:"result"`
+}
+
+func (s *QnapSession) logger() *slog.Logger {
+	if s.options == nil {
+		return nil
+	}
+	return s.options.Logger
+}
+
+// logEnabled checks whether the session has a logger for the level.
+func (s *QnapSession) logEnabled(level slog.Level) bool {
+	logger := s.logger()
+
+	return logger != nil && logger.Enabled(context.Background(), level)
+}
+
+// log writes the message to the session's logger, if any.
+// Never pass any credentials or session IDs as arguments.
+func (s *QnapSession) log(level slog.Level, msg string, args ...any) {
+	if !s.logEnabled(level) {
+		return
+	}
+
+	s.logger().Log(context.Background(), level, msg, args...)
+}
+
+// logRequest writes the details of the API request at debug level.
+func (s *QnapSession) logRequest(req *resty.Request, method, path string, duration time.Duration, res *resty.Response, err error) {
+	if !s.logEnabled(slog.LevelDebug) {
+		return
+	}
+
+	args := []any{
+		"endpoint", path,
+		"method", method,
+		"func", req.QueryParam.Get("func"),
+		"params", redact(req.QueryParam.Encode()),
+		"duration", duration,
+	}
+
+	if err != nil {
+		args = append(args, "error", redact(err.Error()))
+	}
+	if res != nil {
+		args = append(args, "status", res.StatusCode())
+
+		var result resultCheckResponse
+
+		if xml.Unmarshal(res.Body(), &result) == nil {
+			args = append(args, "authPassed", result.AuthPassed, "result", result.Result)
+		}
+	}
+
+	s.log(slog.LevelDebug, "QNAP API request", args...)
+}
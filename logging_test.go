@@ -0,0 +1,50 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogging_Redacted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+
+		switch r.URL.Path {
+		case "/cgi-bin/authLogin.cgi":
+			fmt.Fprint(w, "<QDocRoot><authPassed>1</authPassed><authSid>s3cr3tS1D</authSid></QDocRoot>")
+		default:
+			fmt.Fprint(w, "<QDocRoot><authPassed>1</authPassed><iSCSITargetList></iSCSITargetList><result>0</result></QDocRoot>")
+		}
+	}))
+	defer server.Close()
+
+	var output bytes.Buffer
+
+	s, err := Connect(server.URL, "admin", "p@ssw0rd", &ConfigOptions{
+		APICallTimeout: 5 * time.Second,
+		Logger:         slog.New(slog.NewTextHandler(&output, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	_, err = s.GetISCSITargets()
+	if err != nil {
+		t.Fatalf("Failed to retrieve iSCSI targets: %v", err)
+	}
+
+	logs := output.String()
+
+	if !strings.Contains(logs, "endpoint=cgi-bin/disk/iscsi_portal_setting.cgi") || !strings.Contains(logs, "func=extra_get") {
+		t.Fatalf("Request details missing in logs: %v", logs)
+	}
+	if strings.Contains(logs, "s3cr3tS1D") || strings.Contains(logs, encodePassword("p@ssw0rd")) {
+		t.Fatalf("Credentials leaked into logs: %v", logs)
+	}
+}
@@ -0,0 +1,24 @@
+package manager_test
+
+import (
+	"testing"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+	"github.com/nine-lives-later/go-qnap-disk-manager/qnapfake"
+)
+
+func TestLogin_SystemInfo(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+	fake.SetFirmwareVersion("h5.1.0")
+
+	s := connectFake(t, fake)
+
+	info := s.SystemInfo()
+	if info == nil || info.SerialNumber != "Q000FAKE" || info.ModelName != "TS-FAKE" || info.OSFlavor != manager.OSFlavor_QuTSHero {
+		t.Fatalf("Full system information expected after login: %+v", info)
+	}
+	if caps := s.Capabilities(); caps.ISCSIBackend != "zfs" {
+		t.Fatalf("QuTS hero capabilities expected: %+v", caps)
+	}
+}
@@ -0,0 +1,43 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// MetricsRecorder receives the metrics of every API call, see the metrics package for a Prometheus implementation.
+// It must be safe for concurrent use.
+type MetricsRecorder interface {
+	// ObserveRequest is called after every API request. The error is nil on success.
+	ObserveRequest(host, endpoint, function string, duration time.Duration, err error)
+
+	// ObserveRelogin is called after the session had expired and was logged-in again.
+	ObserveRelogin(host string, err error)
+}
+
+func (s *QnapSession) metricsRecorder() MetricsRecorder {
+	if s.options == nil {
+		return nil
+	}
+	return s.options.MetricsRecorder
+}
+
+// recordRequest reports the API request to the metrics recorder.
+// Besides transport errors, unexpected HTTP status codes and rejected authentication count as error.
+func (s *QnapSession) recordRequest(req *resty.Request, path string, duration time.Duration, res *resty.Response, err error) {
+	recorder := s.metricsRecorder()
+	if recorder == nil {
+		return
+	}
+
+	if err == nil && res.StatusCode() != 200 {
+		err = fmt.Errorf("unexpected HTTP status code: %v", res.StatusCode())
+	}
+	if err == nil && isSessionExpired(res) {
+		err = errAuthenticationInvalid
+	}
+
+	recorder.ObserveRequest(s.host, path, req.QueryParam.Get("func"), duration, err)
+}
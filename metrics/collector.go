@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+)
+
+var (
+	upDesc = prometheus.NewDesc(namespace+"_up",
+		"Whether the last retrieval of the storage information succeeded.",
+		[]string{"host"}, nil)
+
+	poolLabels            = []string{"host", "pool_id"}
+	poolStatusDesc        = newPoolDesc("status", "Status code of the storage pool.")
+	poolCapacityDesc      = newPoolDesc("capacity_bytes", "Capacity of the storage pool.")
+	poolAllocatedDesc     = newPoolDesc("allocated_bytes", "Allocated space of the storage pool.")
+	poolFreesizeDesc      = newPoolDesc("freesize_bytes", "Free space of the storage pool.")
+	poolSnapshotDesc      = newPoolDesc("snapshot_bytes", "Space used by snapshots within the storage pool.")
+	poolOverThresholdDesc = newPoolDesc("over_threshold", "Whether the storage pool exceeds its alert threshold.")
+	lunLabels             = []string{"host", "lun_index", "lun_name", "pool_id"}
+	lunCapacityDesc       = newLUNDesc("capacity_bytes", "Capacity of the LUN.")
+	lunStatusDesc         = newLUNDesc("status", "Status code of the LUN.")
+	lunThresholdDesc      = newLUNDesc("threshold_percent", "Alert threshold of the LUN.")
+	lunAssignedDesc       = newLUNDesc("assigned", "Whether the LUN is assigned to an iSCSI target.")
+
+	targetStatusDesc = prometheus.NewDesc(namespace+"_iscsi_target_status",
+		"Status code of the iSCSI target.",
+		[]string{"host", "target_index", "target_name", "target_iqn"}, nil)
+)
+
+func newPoolDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "storage_pool", name), help, poolLabels, nil)
+}
+
+func newLUNDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "lun", name), help, lunLabels, nil)
+}
+
+// Collector exports the capacity and status of the storage pools, LUNs and iSCSI targets.
+// The information is retrieved from the QNAP system on every scrape.
+type Collector struct {
+	host   string
+	reader manager.Reader
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector creates a new collector retrieving the storage information using the reader,
+// e.g. a *manager.QnapSession or *manager.SessionPool. The host is used as label value.
+func NewCollector(host string, reader manager.Reader) *Collector {
+	return &Collector{
+		host:   host,
+		reader: reader,
+	}
+}
+
+// Describe sends the descriptors of all metrics.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	Describe(ch)
+}
+
+// Describe sends the descriptors of all metrics sent by the Collect functions.
+func Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		upDesc,
+		poolStatusDesc, poolCapacityDesc, poolAllocatedDesc, poolFreesizeDesc, poolSnapshotDesc, poolOverThresholdDesc,
+		lunCapacityDesc, lunStatusDesc, lunThresholdDesc, lunAssignedDesc,
+		targetStatusDesc,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect retrieves the storage information and sends the metrics.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	pools, err := c.reader.GetStoragePools()
+	if err != nil {
+		CollectUp(ch, c.host, false)
+		return
+	}
+
+	luns, err := c.reader.GetLUNs()
+	if err != nil {
+		CollectUp(ch, c.host, false)
+		return
+	}
+
+	targets, err := c.reader.GetISCSITargets()
+	if err != nil {
+		CollectUp(ch, c.host, false)
+		return
+	}
+
+	CollectUp(ch, c.host, true)
+	CollectStoragePools(ch, c.host, pools)
+	CollectLUNs(ch, c.host, luns)
+	CollectISCSITargets(ch, c.host, targets)
+}
+
+// CollectUp sends the metric whether the retrieval of the storage information succeeded.
+func CollectUp(ch chan<- prometheus.Metric, host string, up bool) {
+	value := 0.0
+	if up {
+		value = 1
+	}
+
+	gauge(ch, upDesc, value, host)
+}
+
+// CollectStoragePools sends the metrics of the storage pools.
+func CollectStoragePools(ch chan<- prometheus.Metric, host string, pools []*manager.StoragePool) {
+	for _, pool := range pools {
+		poolID := strconv.Itoa(pool.PoolID)
+
+		gauge(ch, poolStatusDesc, float64(pool.PoolStatus), host, poolID)
+		gauge(ch, poolCapacityDesc, float64(pool.CapacityBytes), host, poolID)
+		gauge(ch, poolAllocatedDesc, float64(pool.AllocatedBytes), host, poolID)
+		gauge(ch, poolFreesizeDesc, float64(pool.FreesizeBytes), host, poolID)
+		gauge(ch, poolSnapshotDesc, float64(pool.SnapshotBytes), host, poolID)
+		gauge(ch, poolOverThresholdDesc, float64(pool.PoolOverThreshold), host, poolID)
+	}
+}
+
+// CollectLUNs sends the metrics of the LUNs.
+func CollectLUNs(ch chan<- prometheus.Metric, host string, luns []*manager.LUN) {
+	for _, lun := range luns {
+		labels := []string{host, strconv.Itoa(lun.LUNIndex), lun.LUNName, strconv.Itoa(lun.PoolID)}
+
+		assigned := 0.0
+		if lun.LUNTargetList.SingleRow != nil {
+			assigned = 1
+		}
+
+		gauge(ch, lunCapacityDesc, float64(lun.CapacityBytes), labels...)
+		gauge(ch, lunStatusDesc, float64(lun.LUNStatus), labels...)
+		gauge(ch, lunThresholdDesc, float64(lun.LUNThresholdPercent), labels...)
+		gauge(ch, lunAssignedDesc, assigned, labels...)
+	}
+}
+
+// CollectISCSITargets sends the metrics of the iSCSI targets.
+func CollectISCSITargets(ch chan<- prometheus.Metric, host string, targets []*manager.ISCSITarget) {
+	for _, target := range targets {
+		gauge(ch, targetStatusDesc, float64(target.TargetStatus), host, strconv.Itoa(target.TargetIndex), target.TargetName, target.TargetIQN)
+	}
+}
+
+func gauge(ch chan<- prometheus.Metric, desc *prometheus.Desc, value float64, labels ...string) {
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...)
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+	"github.com/nine-lives-later/go-qnap-disk-manager/qnapmock"
+)
+
+func TestRecorder(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	recorder, err := NewRecorder(registry)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+
+	recorder.ObserveRequest("nas", "cgi-bin/disk/disk_manage.cgi", "extra_get", time.Second, nil)
+	recorder.ObserveRequest("nas", "cgi-bin/disk/disk_manage.cgi", "extra_get", time.Second, errors.New("failed"))
+	recorder.ObserveRelogin("nas", nil)
+
+	if v := testutil.ToFloat64(recorder.requests.WithLabelValues("nas", "cgi-bin/disk/disk_manage.cgi", "extra_get")); v != 2 {
+		t.Fatalf("Expected 2 requests, got %v", v)
+	}
+	if v := testutil.ToFloat64(recorder.errors.WithLabelValues("nas", "cgi-bin/disk/disk_manage.cgi", "extra_get")); v != 1 {
+		t.Fatalf("Expected 1 error, got %v", v)
+	}
+	if v := testutil.ToFloat64(recorder.relogins.WithLabelValues("nas", "success")); v != 1 {
+		t.Fatalf("Expected 1 re-login, got %v", v)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	client := &qnapmock.Client{
+		GetStoragePoolsFunc: func() ([]*manager.StoragePool, error) {
+			return []*manager.StoragePool{{PoolID: 1, CapacityBytes: 1000, FreesizeBytes: 400}}, nil
+		},
+		GetLUNsFunc: func() ([]*manager.LUN, error) {
+			return []*manager.LUN{{LUNIndex: 3, LUNName: "data", PoolID: 1, CapacityBytes: 100}}, nil
+		},
+	}
+
+	expected := `
+# HELP qnap_storage_pool_freesize_bytes Free space of the storage pool.
+# TYPE qnap_storage_pool_freesize_bytes gauge
+qnap_storage_pool_freesize_bytes{host="nas",pool_id="1"} 400
+# HELP qnap_lun_capacity_bytes Capacity of the LUN.
+# TYPE qnap_lun_capacity_bytes gauge
+qnap_lun_capacity_bytes{host="nas",lun_index="3",lun_name="data",pool_id="1"} 100
+# HELP qnap_up Whether the last retrieval of the storage information succeeded.
+# TYPE qnap_up gauge
+qnap_up{host="nas"} 1
+`
+
+	err := testutil.CollectAndCompare(NewCollector("nas", client), strings.NewReader(expected),
+		"qnap_storage_pool_freesize_bytes", "qnap_lun_capacity_bytes", "qnap_up")
+	if err != nil {
+		t.Fatalf("Unexpected metrics: %v", err)
+	}
+}
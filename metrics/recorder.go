@@ -0,0 +1,86 @@
+// Package metrics provides Prometheus metrics for the QNAP Disk Manager API.
+//
+// The Recorder collects the metrics of every API call and is passed to manager.ConfigOptions.MetricsRecorder.
+// The Collector exports the capacity and status of the storage pools and LUNs of a QNAP system.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+)
+
+const namespace = "qnap"
+
+// Recorder records the metrics of the API calls of one or more sessions.
+type Recorder struct {
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	relogins *prometheus.CounterVec
+}
+
+var _ manager.MetricsRecorder = (*Recorder)(nil)
+
+// NewRecorder creates a new recorder and registers its metrics.
+func NewRecorder(registerer prometheus.Registerer) (*Recorder, error) {
+	labels := []string{"host", "endpoint", "func"}
+
+	r := &Recorder{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "api",
+			Name:      "requests_total",
+			Help:      "Number of API requests sent to the QNAP system.",
+		}, labels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "api",
+			Name:      "request_errors_total",
+			Help:      "Number of failed API requests, including unexpected HTTP status codes and rejected authentication.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "api",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of the API requests sent to the QNAP system.",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		}, labels),
+		relogins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "api",
+			Name:      "relogins_total",
+			Help:      "Number of logins performed after the session had expired.",
+		}, []string{"host", "result"}),
+	}
+
+	for _, c := range []prometheus.Collector{r.requests, r.errors, r.duration, r.relogins} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// ObserveRequest records the API request.
+func (r *Recorder) ObserveRequest(host, endpoint, function string, duration time.Duration, err error) {
+	r.requests.WithLabelValues(host, endpoint, function).Inc()
+	r.duration.WithLabelValues(host, endpoint, function).Observe(duration.Seconds())
+
+	if err != nil {
+		r.errors.WithLabelValues(host, endpoint, function).Inc()
+	}
+}
+
+// ObserveRelogin records the re-login after the session had expired.
+func (r *Recorder) ObserveRelogin(host string, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+
+	r.relogins.WithLabelValues(host, result).Inc()
+}
@@ -0,0 +1,308 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned when using a session pool after it has been closed.
+var ErrPoolClosed = errors.New("session pool closed")
+
+// PoolOptions contains the settings of a session pool.
+type PoolOptions struct {
+	Size                int           // number of sessions to keep logged-in
+	HealthCheckInterval time.Duration // idle sessions are checked before being handed out again, zero to disable
+}
+
+var defaultPoolOptions = PoolOptions{
+	Size:                4,
+	HealthCheckInterval: 60 * time.Second,
+}
+
+// SessionPool manages multiple logged-in sessions to the same QNAP system,
+// e.g. to spread many parallel read calls over several session IDs.
+// It is safe for concurrent use by multiple goroutines.
+type SessionPool struct {
+	host          string
+	credentials   CredentialProvider
+	configOptions *ConfigOptions
+	poolOptions   *PoolOptions
+
+	idle   chan *pooledSession
+	shared *sessionShared
+
+	lock     sync.Mutex
+	sessions []*pooledSession
+	closed   bool
+}
+
+type pooledSession struct {
+	session  *QnapSession
+	lastUsed time.Time
+	broken   bool
+}
+
+// NewSessionPool creates a new pool and logs-in all of its sessions.
+func NewSessionPool(host string, credentials CredentialProvider, configOptions *ConfigOptions, poolOptions *PoolOptions) (*SessionPool, error) {
+	if poolOptions == nil {
+		poolOptions = &defaultPoolOptions
+	}
+	if poolOptions.Size <= 0 {
+		return nil, fmt.Errorf("invalid pool size: %v", poolOptions.Size)
+	}
+
+	pool := &SessionPool{
+		host:          host,
+		credentials:   credentials,
+		configOptions: configOptions,
+		poolOptions:   poolOptions,
+		idle:          make(chan *pooledSession, poolOptions.Size),
+		shared:        newSessionShared(configOptions),
+	}
+
+	for i := 0; i < poolOptions.Size; i++ {
+		session, err := connect(host, credentials, configOptions, pool.shared)
+		if err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("failed to connect session %v of %v: %w", i+1, poolOptions.Size, err)
+		}
+
+		ps := &pooledSession{session: session, lastUsed: time.Now()}
+
+		pool.sessions = append(pool.sessions, ps)
+		pool.idle <- ps
+	}
+
+	return pool, nil
+}
+
+// String returns the pool's hostname.
+func (p *SessionPool) String() string {
+	return p.host
+}
+
+// Close logs-out all sessions of the pool, after waiting for the running calls to finish.
+func (p *SessionPool) Close() error {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.lock.Unlock()
+
+	// wait for all sessions to be returned
+	for range p.sessions {
+		<-p.idle
+	}
+
+	var errs []string
+
+	for _, ps := range p.sessions {
+		if err := ps.session.Logout(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	// hand the sessions out again, so waiting calls fail with ErrPoolClosed
+	for _, ps := range p.sessions {
+		p.idle <- ps
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to logout %v of %v sessions: %v", len(errs), len(p.sessions), errs)
+	}
+
+	return nil
+}
+
+// Do runs the function with a healthy session of the pool, waiting for a free one if necessary.
+// The session must not be used after the function returns.
+func (p *SessionPool) Do(fn func(s *QnapSession) error) error {
+	ps, err := p.acquire()
+	if err != nil {
+		return err
+	}
+
+	err = fn(ps.session)
+
+	p.release(ps, err)
+
+	return err
+}
+
+func (p *SessionPool) acquire() (*pooledSession, error) {
+	if p.isClosed() {
+		return nil, ErrPoolClosed
+	}
+
+	ps := <-p.idle
+
+	if p.isClosed() {
+		p.idle <- ps
+		return nil, ErrPoolClosed
+	}
+
+	if err := p.ensureHealthy(ps); err != nil {
+		p.idle <- ps
+		return nil, err
+	}
+
+	return ps, nil
+}
+
+func (p *SessionPool) release(ps *pooledSession, err error) {
+	ps.lastUsed = time.Now()
+	ps.broken = isBrokenSessionError(err) // check the session before handing it out again
+
+	p.idle <- ps
+}
+
+// ensureHealthy checks broken and long idle sessions, and logs them in again if required.
+func (p *SessionPool) ensureHealthy(ps *pooledSession) error {
+	idle := p.poolOptions.HealthCheckInterval > 0 && time.Since(ps.lastUsed) > p.poolOptions.HealthCheckInterval
+
+	if ps.session.SessionID() != "" && !ps.broken && !idle {
+		return nil
+	}
+
+	if ps.session.SessionID() != "" {
+		if _, err := ps.session.GetSystemInfo(); err == nil {
+			ps.broken = false
+			return nil
+		}
+	}
+
+	// login again
+	if err := ps.session.loginWithProvider(context.Background()); err != nil {
+		return fmt.Errorf("failed to login pooled session again: %w", err)
+	}
+
+	ps.broken = false
+	return nil
+}
+
+// isBrokenSessionError returns true if the error indicates a broken connection or session,
+// as opposed to errors of the call itself, like missing permissions or invalid arguments.
+func isBrokenSessionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var urlErr *url.Error
+
+	return errors.As(err, &urlErr) ||
+		errors.Is(err, errAuthenticationInvalid) ||
+		errors.Is(err, ErrAuthenticationFailed) ||
+		errors.Is(err, ErrSecurityCodeRequired) ||
+		errors.Is(err, ErrSecurityCodeInvalid)
+}
+
+func (p *SessionPool) isClosed() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.closed
+}
+
+// GetSystemInfo retrieves the model, firmware and resource information of the QNAP system.
+func (p *SessionPool) GetSystemInfo() (*SystemInfo, error) {
+	return p.GetSystemInfoContext(context.Background())
+}
+
+// GetSystemInfoContext retrieves the model, firmware and resource information of the QNAP system.
+func (p *SessionPool) GetSystemInfoContext(ctx context.Context) (info *SystemInfo, err error) {
+	err = p.Do(func(s *QnapSession) error {
+		info, err = s.GetSystemInfoContext(ctx)
+		return err
+	})
+	return info, err
+}
+
+// GetStoragePools retrieves the list of storage pools.
+func (p *SessionPool) GetStoragePools() ([]*StoragePool, error) {
+	return p.GetStoragePoolsContext(context.Background())
+}
+
+// GetStoragePoolsContext retrieves the list of storage pools.
+func (p *SessionPool) GetStoragePoolsContext(ctx context.Context) (pools []*StoragePool, err error) {
+	err = p.Do(func(s *QnapSession) error {
+		pools, err = s.GetStoragePoolsContext(ctx)
+		return err
+	})
+	return pools, err
+}
+
+// GetStoragePoolsEx retrieves the list of storage pools, ordered like returned by the QNAP system.
+func (p *SessionPool) GetStoragePoolsEx(options *StoragePoolOptions) ([]*StoragePool, error) {
+	return p.GetStoragePoolsExContext(context.Background(), options)
+}
+
+// GetStoragePoolsExContext retrieves the list of storage pools, ordered like returned by the QNAP system.
+func (p *SessionPool) GetStoragePoolsExContext(ctx context.Context, options *StoragePoolOptions) (pools []*StoragePool, err error) {
+	err = p.Do(func(s *QnapSession) error {
+		pools, err = s.GetStoragePoolsExContext(ctx, options)
+		return err
+	})
+	return pools, err
+}
+
+// GetStoragePoolIDs retrieves the IDs of the storage pools, without their information.
+func (p *SessionPool) GetStoragePoolIDs() ([]int, error) {
+	return p.GetStoragePoolIDsContext(context.Background())
+}
+
+// GetStoragePoolIDsContext retrieves the IDs of the storage pools, without their information.
+func (p *SessionPool) GetStoragePoolIDsContext(ctx context.Context) (poolIDs []int, err error) {
+	err = p.Do(func(s *QnapSession) error {
+		poolIDs, err = s.GetStoragePoolIDsContext(ctx)
+		return err
+	})
+	return poolIDs, err
+}
+
+// GetLUNs retrieves the list of all storage LUNs.
+func (p *SessionPool) GetLUNs() ([]*LUN, error) {
+	return p.GetLUNsContext(context.Background())
+}
+
+// GetLUNsContext retrieves the list of all storage LUNs.
+func (p *SessionPool) GetLUNsContext(ctx context.Context) (luns []*LUN, err error) {
+	err = p.Do(func(s *QnapSession) error {
+		luns, err = s.GetLUNsContext(ctx)
+		return err
+	})
+	return luns, err
+}
+
+// GetLUNByIndex retrieves the a storage LUN by its LUN ID (not volume ID!)
+func (p *SessionPool) GetLUNByIndex(lunIndex int) (*LUN, error) {
+	return p.GetLUNByIndexContext(context.Background(), lunIndex)
+}
+
+// GetLUNByIndexContext retrieves the a storage LUN by its LUN ID (not volume ID!)
+func (p *SessionPool) GetLUNByIndexContext(ctx context.Context, lunIndex int) (lun *LUN, err error) {
+	err = p.Do(func(s *QnapSession) error {
+		lun, err = s.GetLUNByIndexContext(ctx, lunIndex)
+		return err
+	})
+	return lun, err
+}
+
+// GetISCSITargets retrieves the list of all iSCSI targets.
+func (p *SessionPool) GetISCSITargets() ([]*ISCSITarget, error) {
+	return p.GetISCSITargetsContext(context.Background())
+}
+
+// GetISCSITargetsContext retrieves the list of all iSCSI targets.
+func (p *SessionPool) GetISCSITargetsContext(ctx context.Context) (targets []*ISCSITarget, err error) {
+	err = p.Do(func(s *QnapSession) error {
+		targets, err = s.GetISCSITargetsContext(ctx)
+		return err
+	})
+	return targets, err
+}
@@ -0,0 +1,26 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+)
+
+func TestIsBrokenSessionError(t *testing.T) {
+	if isBrokenSessionError(nil) {
+		t.Fatal("No error is not broken")
+	}
+	if isBrokenSessionError(fmt.Errorf("failed to delete LUN: %w", ErrPermissionDenied)) {
+		t.Fatal("Missing permissions do not break the session")
+	}
+	if isBrokenSessionError(fmt.Errorf("failed to perform request: unexpected result code: -1")) {
+		t.Fatal("Rejected calls do not break the session")
+	}
+	if !isBrokenSessionError(fmt.Errorf("failed to perform request: %w: <QDocRoot/>", errAuthenticationInvalid)) {
+		t.Fatal("Rejected sessions are broken")
+	}
+	if !isBrokenSessionError(fmt.Errorf("failed to perform request: %w", &url.Error{Op: "Post", URL: "https://storage", Err: errors.New("connection reset")})) {
+		t.Fatal("Transport errors break the session")
+	}
+}
@@ -0,0 +1,271 @@
+package manager
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrProfileNotFound is returned when the requested profile is not defined in the profiles file.
+var ErrProfileNotFound = errors.New("profile not found")
+
+// Profiles is the content of a profiles file, defining named QNAP systems, e.g.:
+//
+//	default: storage1
+//	profiles:
+//	  storage1:
+//	    host: storage1.example.com:443
+//	    credentials:
+//	      source: file
+//	      username_file: /secrets/storage1/username
+//	      password_file: /secrets/storage1/password
+//	    tls:
+//	      fingerprint: "ab:cd:..."
+//	    timeout: 90s
+type Profiles struct {
+	Default  string              `yaml:"default"`
+	Profiles map[string]*Profile `yaml:"profiles"`
+}
+
+// Profile defines the connection to a single QNAP system.
+type Profile struct {
+	Host        string             `yaml:"host"`
+	Credentials ProfileCredentials `yaml:"credentials"`
+	TLS         ProfileTLS         `yaml:"tls"`
+	Timeout     time.Duration      `yaml:"timeout"`     // API call timeout, defaults to 60s
+	RememberMe  bool               `yaml:"remember_me"` // request a long-lived login token
+	ProxyURL    string             `yaml:"proxy"`
+	Retry       *ProfileRetry      `yaml:"retry"` // retry transient errors, omit to disable retries
+}
+
+// ProfileCredentials defines where the credentials of a profile are read from.
+type ProfileCredentials struct {
+	Source       string `yaml:"source"` // static, env or file; defaults to env
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	UsernameEnv  string `yaml:"username_env"` // defaults to QNAP_USER
+	PasswordEnv  string `yaml:"password_env"` // defaults to QNAP_PWD
+	UsernameFile string `yaml:"username_file"`
+	PasswordFile string `yaml:"password_file"`
+}
+
+// ProfileTLS contains the TLS settings of a profile.
+type ProfileTLS struct {
+	Insecure      bool   `yaml:"insecure"`
+	CAFile        string `yaml:"ca_file"`
+	Fingerprint   string `yaml:"fingerprint"`
+	ServerName    string `yaml:"server_name"`
+	MinTLSVersion string `yaml:"min_version"` // 1.2 or 1.3
+}
+
+// ProfileRetry contains the retry settings of a profile.
+type ProfileRetry struct {
+	MaxAttempts    int           `yaml:"max_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	RetryMutations bool          `yaml:"retry_mutations"`
+}
+
+// DefaultProfilesFile returns the location of the profiles file: the QNAP_PROFILES
+// environment variable, or qnap/profiles.yaml within the user's configuration directory.
+func DefaultProfilesFile() (string, error) {
+	if file := os.Getenv("QNAP_PROFILES"); file != "" {
+		return file, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate profiles file: %w", err)
+	}
+
+	return filepath.Join(dir, "qnap", "profiles.yaml"), nil
+}
+
+// ReadProfiles reads and validates the profiles file.
+func ReadProfiles(file string) (*Profiles, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles file: %w", err)
+	}
+
+	return ParseProfiles(data)
+}
+
+// ParseProfiles parses and validates the content of a profiles file.
+func ParseProfiles(data []byte) (*Profiles, error) {
+	var profiles Profiles
+
+	if err := yaml.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse profiles: %w", err)
+	}
+
+	for _, name := range profiles.Names() {
+		if err := profiles.Profiles[name].validate(); err != nil {
+			return nil, fmt.Errorf("invalid profile %v: %w", name, err)
+		}
+	}
+	if profiles.Default != "" && profiles.Profiles[profiles.Default] == nil {
+		return nil, fmt.Errorf("invalid default profile %v: %w", profiles.Default, ErrProfileNotFound)
+	}
+
+	return &profiles, nil
+}
+
+// Names returns the sorted names of all profiles.
+func (p *Profiles) Names() []string {
+	names := make([]string, 0, len(p.Profiles))
+	for name := range p.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the profile with the name, or the default profile if the name is empty.
+func (p *Profiles) Get(name string) (*Profile, error) {
+	if name == "" {
+		name = p.Default
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no profile name given and no default profile defined: %w", ErrProfileNotFound)
+	}
+
+	profile := p.Profiles[name]
+	if profile == nil {
+		return nil, fmt.Errorf("%w: %v", ErrProfileNotFound, name)
+	}
+
+	return profile, nil
+}
+
+// LoadProfile connects to the QNAP system of the named profile, defined in the default profiles file.
+// The default profile is used if the name is empty.
+func LoadProfile(name string) (*QnapSession, error) {
+	file, err := DefaultProfilesFile()
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadProfileFromFile(file, name)
+}
+
+// LoadProfileFromFile connects to the QNAP system of the named profile, defined in the profiles file.
+// The default profile is used if the name is empty.
+func LoadProfileFromFile(file, name string) (*QnapSession, error) {
+	profiles, err := ReadProfiles(file)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := profiles.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return profile.Connect()
+}
+
+// Connect connects to the QNAP system of the profile.
+func (p *Profile) Connect() (*QnapSession, error) {
+	options, err := p.ConfigOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	return ConnectWithCredentials(p.Host, p.CredentialProvider(), options)
+}
+
+// CredentialProvider returns the provider of the profile's credentials.
+func (p *Profile) CredentialProvider() CredentialProvider {
+	c := p.Credentials
+
+	switch c.Source {
+	case "static":
+		return StaticCredentials{Username: c.Username, Password: c.Password}
+	case "file":
+		return NewFileCredentials(c.UsernameFile, c.PasswordFile)
+	default:
+		return EnvCredentials{UsernameVar: c.UsernameEnv, PasswordVar: c.PasswordEnv}
+	}
+}
+
+// ConfigOptions returns the config options of the profile. Further options,
+// like Logger or MetricsRecorder, can be set on the result before connecting.
+func (p *Profile) ConfigOptions() (*ConfigOptions, error) {
+	options := &ConfigOptions{
+		APICallTimeout:              p.Timeout,
+		IgnoreInvalidSSLCertificate: p.TLS.Insecure,
+		CACertificateFile:           p.TLS.CAFile,
+		CertificateFingerprint:      p.TLS.Fingerprint,
+		TLSServerName:               p.TLS.ServerName,
+		RememberMe:                  p.RememberMe,
+		ProxyURL:                    p.ProxyURL,
+	}
+
+	if options.APICallTimeout == 0 {
+		options.APICallTimeout = defaultConfigOptions.APICallTimeout
+	}
+
+	switch p.TLS.MinTLSVersion {
+	case "":
+	case "1.2":
+		options.MinTLSVersion = tls.VersionTLS12
+	case "1.3":
+		options.MinTLSVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported minimum TLS version: %v", p.TLS.MinTLSVersion)
+	}
+
+	if p.Retry != nil {
+		policy := DefaultRetryPolicy
+		if p.Retry.MaxAttempts > 0 {
+			policy.MaxAttempts = p.Retry.MaxAttempts
+		}
+		if p.Retry.InitialBackoff > 0 {
+			policy.InitialBackoff = p.Retry.InitialBackoff
+		}
+		if p.Retry.MaxBackoff > 0 {
+			policy.MaxBackoff = p.Retry.MaxBackoff
+		}
+		policy.RetryMutations = p.Retry.RetryMutations
+
+		options.RetryPolicy = &policy
+	}
+
+	return options, nil
+}
+
+func (p *Profile) validate() error {
+	if p == nil {
+		return fmt.Errorf("empty profile")
+	}
+	if p.Host == "" {
+		return fmt.Errorf("missing host")
+	}
+
+	switch c := p.Credentials; c.Source {
+	case "", "env":
+	case "static":
+		if c.Username == "" || c.Password == "" {
+			return fmt.Errorf("static credentials require username and password")
+		}
+	case "file":
+		if c.UsernameFile == "" || c.PasswordFile == "" {
+			return fmt.Errorf("file credentials require username_file and password_file")
+		}
+	default:
+		return fmt.Errorf("unknown credential source: %v", c.Source)
+	}
+
+	if _, err := p.ConfigOptions(); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseProfiles(t *testing.T) {
+	profiles, err := ParseProfiles([]byte(`
+default: storage1
+profiles:
+  storage1:
+    host: storage1:443
+    credentials:
+      source: file
+      username_file: /secrets/username
+      password_file: /secrets/password
+    tls:
+      fingerprint: "00:11"
+      min_version: "1.3"
+    timeout: 90s
+    retry:
+      max_attempts: 5
+  storage2:
+    host: storage2:443
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse profiles: %v", err)
+	}
+
+	profile, err := profiles.Get("")
+	if err != nil {
+		t.Fatalf("Failed to get default profile: %v", err)
+	}
+	if profile.Host != "storage1:443" || profile.Timeout != 90*time.Second {
+		t.Fatalf("Wrong default profile: %+v", profile)
+	}
+
+	options, err := profile.ConfigOptions()
+	if err != nil {
+		t.Fatalf("Failed to build config options: %v", err)
+	}
+	if options.CertificateFingerprint != "00:11" || options.RetryPolicy == nil || options.RetryPolicy.MaxAttempts != 5 {
+		t.Fatalf("Wrong config options: %+v", options)
+	}
+	if _, ok := profile.CredentialProvider().(*FileCredentials); !ok {
+		t.Fatalf("File credentials expected: %T", profile.CredentialProvider())
+	}
+
+	profile, _ = profiles.Get("storage2")
+	if _, ok := profile.CredentialProvider().(EnvCredentials); !ok {
+		t.Fatalf("Environment credentials expected: %T", profile.CredentialProvider())
+	}
+
+	if _, err := profiles.Get("storage3"); !errors.Is(err, ErrProfileNotFound) {
+		t.Fatalf("ErrProfileNotFound expected: %v", err)
+	}
+}
+
+func TestParseProfiles_Invalid(t *testing.T) {
+	for _, data := range []string{
+		"profiles:\n  nas:\n    credentials:\n      source: env\n",
+		"profiles:\n  nas:\n    host: nas\n    credentials:\n      source: vault\n",
+		"profiles:\n  nas:\n    host: nas\n    tls:\n      min_version: \"1.0\"\n",
+		"default: other\nprofiles:\n  nas:\n    host: nas\n",
+	} {
+		if _, err := ParseProfiles([]byte(data)); err == nil {
+			t.Fatalf("Error expected for profiles:\n%v", data)
+		}
+	}
+}
+
+func TestLoadProfileFromFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+
+		if r.FormValue("user") != "admin" {
+			fmt.Fprint(w, "<QDocRoot><authPassed>0</authPassed></QDocRoot>")
+			return
+		}
+		fmt.Fprint(w, "<QDocRoot><authPassed>1</authPassed><authSid>abcdef</authSid><isAdmin>1</isAdmin></QDocRoot>")
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "profiles.yaml")
+	data := fmt.Sprintf("profiles:\n  test:\n    host: %v\n    credentials:\n      source: static\n      username: admin\n      password: admin\n", server.URL)
+	if err := os.WriteFile(file, []byte(data), 0600); err != nil {
+		t.Fatalf("Failed to write profiles file: %v", err)
+	}
+
+	s, err := LoadProfileFromFile(file, "test")
+	if err != nil {
+		t.Fatalf("Failed to load profile: %v", err)
+	}
+	if s.SessionID() != "abcdef" {
+		t.Fatalf("Wrong session ID: %v", s.SessionID())
+	}
+}
@@ -0,0 +1,508 @@
+// Package qnapfake provides an in-process fake of the QNAP API for integration tests,
+// e.g. of tools built on the library, without the need for a real QNAP system.
+//
+// The fake keeps the storage pools, LUNs and iSCSI targets in memory and supports
+// the API calls used by the library. New LUNs and targets are ready immediately.
+package qnapfake
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+)
+
+// Server is a fake QNAP system serving the API via HTTP.
+// It is safe for concurrent use by multiple goroutines.
+type Server struct {
+	URL string // base URL of the fake, to be used as host
+
+	server   *httptest.Server
+	username string
+	password string
+
+	lock       sync.Mutex
+	sessions   map[string]bool
+	nextID     int
+	pools      map[int]*manager.StoragePool
+	luns       map[int]*manager.LUN
+	targets    map[int]*manager.ISCSITarget
+	nextLUN    int
+	nextTarget int
+	firmware   string
+
+	interceptor Interceptor
+}
+
+// Interceptor is called for every request before the fake handles it, e.g. to record the requests
+// or to inject faults. It may write its own response and return true to skip the fake.
+type Interceptor func(w http.ResponseWriter, r *http.Request) bool
+
+// NewServer starts a new fake accepting the credentials, with a single
+// storage pool #1 of one TiB capacity. The fake must be closed after use.
+func NewServer(username, password string) *Server {
+	s := &Server{
+		username: username,
+		password: password,
+		sessions: map[string]bool{},
+		pools:    map[int]*manager.StoragePool{},
+		luns:     map[int]*manager.LUN{},
+		targets:  map[int]*manager.ISCSITarget{},
+		nextLUN:  1,
+		firmware: "5.1.0",
+	}
+
+	s.AddStoragePool(1, 1<<40)
+
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL
+
+	return s
+}
+
+// Close shuts down the fake.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// AddStoragePool adds a storage pool with the capacity in bytes.
+func (s *Server) AddStoragePool(poolID int, capacityBytes int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.pools[poolID] = &manager.StoragePool{
+		PoolID:        poolID,
+		PoolStatus:    0,
+		CapacityBytes: capacityBytes,
+		FreesizeBytes: capacityBytes,
+	}
+}
+
+// SetFirmwareVersion changes the reported firmware version, e.g. "h5.1.0" for QuTS hero.
+// It is reported from the next login on.
+func (s *Server) SetFirmwareVersion(version string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.firmware = version
+}
+
+// SetInterceptor sets the function called for every request, nil to remove it.
+// The interceptor is called concurrently, without holding any lock of the fake.
+func (s *Server) SetInterceptor(interceptor Interceptor) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.interceptor = interceptor
+}
+
+// ExpireSessions invalidates all sessions, like a restart of the NAS.
+// The next request of a client is rejected and has to log-in again.
+func (s *Server) ExpireSessions() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.sessions = map[string]bool{}
+}
+
+// WriteResult writes the response of an authenticated call with the result code,
+// e.g. "-1" for an interceptor to inject a rejected call.
+func WriteResult(w http.ResponseWriter, result string) {
+	writeResponse(w, &response{AuthPassed: 1, Result: result})
+}
+
+// LUNs returns a copy of all LUNs, ordered by index.
+func (s *Server) LUNs() []*manager.LUN {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.sortedLUNs()
+}
+
+// ISCSITargets returns a copy of all iSCSI targets, ordered by index.
+func (s *Server) ISCSITargets() []*manager.ISCSITarget {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.sortedTargets()
+}
+
+func (s *Server) sortedLUNs() []*manager.LUN {
+	luns := make([]*manager.LUN, 0, len(s.luns))
+	for _, lun := range s.luns {
+		c := *lun
+		luns = append(luns, &c)
+	}
+	sort.Slice(luns, func(i, j int) bool { return luns[i].LUNIndex < luns[j].LUNIndex })
+	return luns
+}
+
+func (s *Server) sortedTargets() []*manager.ISCSITarget {
+	targets := make([]*manager.ISCSITarget, 0, len(s.targets))
+	for _, target := range s.targets {
+		c := *target
+		targets = append(targets, &c)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].TargetIndex < targets[j].TargetIndex })
+	return targets
+}
+
+type response struct {
+	AuthPassed int
+	Body       interface{} // struct whose fields become the elements of the document
+	Result     string
+}
+
+// MarshalXML writes the response as QDocRoot document, with the fields of the body inlined.
+func (r *response) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	var body bytes.Buffer
+
+	if r.Body != nil {
+		if err := xml.NewEncoder(&body).EncodeElement(r.Body, xml.StartElement{Name: xml.Name{Local: "body"}}); err != nil {
+			return err
+		}
+	}
+
+	doc := struct {
+		XMLName    xml.Name `xml:"QDocRoot"`
+		AuthPassed int      `xml:"authPassed"`
+		Body       string   `xml:",innerxml"`
+		Result     string   `xml:"result,omitempty"`
+	}{
+		AuthPassed: r.AuthPassed,
+		Body:       strings.TrimSuffix(strings.TrimPrefix(body.String(), "<body>"), "</body>"),
+		Result:     r.Result,
+	}
+
+	return e.Encode(doc)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	interceptor := s.interceptor
+	s.lock.Unlock()
+
+	if interceptor != nil && interceptor(w, r) {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var res *response
+
+	switch r.URL.Path {
+	case "/cgi-bin/authLogin.cgi":
+		res = s.login(r)
+	case "/cgi-bin/authLogout.cgi":
+		delete(s.sessions, r.FormValue("sid"))
+		res = &response{AuthPassed: 1}
+	default:
+		if !s.sessions[r.FormValue("sid")] {
+			res = &response{AuthPassed: 0}
+			break
+		}
+
+		res = s.handle(r)
+		if res == nil {
+			http.NotFound(w, r)
+			return
+		}
+		res.AuthPassed = 1
+	}
+
+	writeResponse(w, res)
+}
+
+func writeResponse(w http.ResponseWriter, res *response) {
+	w.Header().Set("Content-Type", "text/xml")
+	if err := xml.NewEncoder(w).Encode(res); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+type systemInfo struct {
+	Username string `xml:"username"`
+	Hostname string `xml:"hostname"`
+	IsAdmin  int    `xml:"isAdmin"`
+	Model    struct {
+		ModelName string `xml:"modelName"`
+	} `xml:"model"`
+	Firmware struct {
+		Version string `xml:"version"`
+		Build   string `xml:"build"`
+	} `xml:"firmware"`
+}
+
+func (s *Server) systemInfo() *systemInfo {
+	info := &systemInfo{Username: s.username, Hostname: "qnapfake", IsAdmin: 1}
+	info.Model.ModelName = "TS-FAKE"
+	info.Firmware.Version = s.firmware
+	info.Firmware.Build = "20230101"
+	return info
+}
+
+func (s *Server) login(r *http.Request) *response {
+	password, _ := base64.StdEncoding.DecodeString(r.FormValue("pwd"))
+
+	if r.FormValue("user") != s.username || string(password) != s.password {
+		return &response{AuthPassed: 0}
+	}
+
+	s.nextID++
+	sessionID := fmt.Sprintf("fake%08d", s.nextID)
+	s.sessions[sessionID] = true
+
+	return &response{AuthPassed: 1, Body: struct {
+		SessionID string `xml:"authSid"`
+		*systemInfo
+	}{sessionID, s.systemInfo()}}
+}
+
+func (s *Server) handle(r *http.Request) *response {
+	function := r.FormValue("func")
+
+	switch r.URL.Path {
+	case "/cgi-bin/management/manaRequest.cgi":
+		return &response{Body: struct {
+			*systemInfo
+			SerialNumber string `xml:"func>ownContent>root>serial_number"`
+		}{s.systemInfo(), "Q000FAKE"}}
+
+	case "/cgi-bin/disk/disk_manage.cgi":
+		switch r.FormValue("store") {
+		case "poolList":
+			return s.listPools()
+		case "poolInfo":
+			return s.getPool(atoi(r.FormValue("poolID")))
+		}
+
+	case "/cgi-bin/disk/iscsi_portal_setting.cgi":
+		switch {
+		case r.FormValue("lunList") == "1":
+			return &response{Result: "0", Body: struct {
+				LUNs []*manager.LUN `xml:"iSCSILUNList>LUNInfo"`
+			}{s.sortedLUNs()}}
+		case r.FormValue("store") == "lunInfo":
+			return &response{Result: "0", Body: struct {
+				LUN *manager.LUN `xml:"LUNInfo>row"`
+			}{s.luns[atoi(r.FormValue("lunID"))]}}
+		case r.FormValue("targetList") == "1":
+			return &response{Result: "0", Body: struct {
+				Targets []*manager.ISCSITarget `xml:"iSCSITargetList>targetInfo"`
+			}{s.sortedTargets()}}
+		}
+
+	case "/cgi-bin/disk/iscsi_lun_setting.cgi":
+		switch function {
+		case "add_lun":
+			return s.createLUN(r)
+		case "remove_lun":
+			return s.deleteLUN(atoi(r.FormValue("LUNIndex")))
+		case "add_init":
+			return s.addInitiator(atoi(r.FormValue("LUNIndex")), r.FormValue("initiatorIQN"), atoi(r.FormValue("accessMode")))
+		}
+
+	case "/cgi-bin/disk/iscsi_target_setting.cgi":
+		switch function {
+		case "add_lun":
+			return s.assignLUN(atoi(r.FormValue("LUNIndex")), atoi(r.FormValue("targetIndex")))
+		case "remove_lun":
+			return s.unassignLUN(atoi(r.FormValue("LUNIndex")), atoi(r.FormValue("targetIndex")))
+		case "add_target":
+			return s.createTarget(r.FormValue("targetName"), r.FormValue("targetAlias"))
+		case "remove_target":
+			return s.deleteTarget(atoi(r.FormValue("targetIndex")))
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) listPools() *response {
+	type row struct {
+		PoolID int `xml:"poolID"`
+	}
+
+	var rows []row
+	for poolID := range s.pools {
+		rows = append(rows, row{poolID})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].PoolID < rows[j].PoolID })
+
+	return &response{Result: "0", Body: struct {
+		Rows []row `xml:"Pool_Index>row"`
+	}{rows}}
+}
+
+func (s *Server) getPool(poolID int) *response {
+	pool, ok := s.pools[poolID]
+	if !ok {
+		return &response{Result: "-1"}
+	}
+
+	// the allocated space is the sum of all LUNs of the pool
+	c := *pool
+	for _, lun := range s.luns {
+		if lun.PoolID == poolID {
+			c.AllocatedBytes += lun.CapacityBytes
+		}
+	}
+	c.FreesizeBytes = c.CapacityBytes - c.AllocatedBytes
+
+	return &response{Result: "0", Body: struct {
+		Pool *manager.StoragePool `xml:"Pool_Index>row"`
+	}{&c}}
+}
+
+func (s *Server) createLUN(r *http.Request) *response {
+	poolID := atoi(r.FormValue("poolID"))
+	if _, ok := s.pools[poolID]; !ok {
+		return &response{Result: "-1"}
+	}
+
+	index := s.nextLUN
+	s.nextLUN++
+
+	s.luns[index] = &manager.LUN{
+		LUNIndex:            index,
+		LUNName:             r.FormValue("LUNName"),
+		LUNPath:             r.FormValue("LUNPath"),
+		LUNThinAllocate:     r.FormValue("LUNThinAllocate") == "1",
+		CapacityBytes:       int64(atoi(r.FormValue("LUNCapacity"))) << 30,
+		LUNThresholdPercent: atoi(r.FormValue("lv_threshold")),
+		LUNNAA:              fmt.Sprintf("6e843b6%025x", index),
+		LUNSerialNum:        fmt.Sprintf("fake-%08d", index),
+		LUNSectorSize:       atoi(r.FormValue("LUNSectorSize")),
+		SsdCache:            r.FormValue("lv_ifssd"),
+		PoolID:              poolID,
+		VolumeID:            index,
+	}
+
+	return &response{Result: strconv.Itoa(index)}
+}
+
+func (s *Server) deleteLUN(lunIndex int) *response {
+	if _, ok := s.luns[lunIndex]; !ok {
+		return &response{Result: "-1"}
+	}
+
+	delete(s.luns, lunIndex)
+
+	return &response{Result: "0"}
+}
+
+func (s *Server) assignLUN(lunIndex, targetIndex int) *response {
+	lun, ok := s.luns[lunIndex]
+	if !ok || s.targets[targetIndex] == nil || lun.LUNTargetList.SingleRow != nil {
+		return &response{Result: "-1"}
+	}
+
+	// LUN numbers are counted per target
+	lunNumber := 0
+	for _, other := range s.luns {
+		if row := other.LUNTargetList.SingleRow; row != nil && row.TargetIndex == targetIndex && row.LUNNumber >= lunNumber {
+			lunNumber = row.LUNNumber + 1
+		}
+	}
+
+	lun.LUNTargetList.SingleRow = &struct {
+		TargetIndex int  `xml:"targetIndex"`
+		LUNNumber   int  `xml:"LUNNumber"`
+		LUNEnable   bool `xml:"LUNEnable"`
+	}{TargetIndex: targetIndex, LUNNumber: lunNumber, LUNEnable: true}
+	lun.LUNNumber = lunNumber
+	lun.LUNAttachedTarget = 1
+
+	return &response{Result: strconv.Itoa(lunNumber)}
+}
+
+func (s *Server) addInitiator(lunIndex int, iqn string, accessMode int) *response {
+	lun, ok := s.luns[lunIndex]
+	if !ok || iqn == "" {
+		return &response{Result: "-1"}
+	}
+
+	// initiator indexes are counted per LUN
+	initiatorIndex := 0
+	for _, initiator := range lun.LUNInitiatorList.LUNInitInfo {
+		if initiator.InitiatorIQN == iqn {
+			return &response{Result: "-1"}
+		}
+		if initiator.InitiatorIndex >= initiatorIndex {
+			initiatorIndex = initiator.InitiatorIndex + 1
+		}
+	}
+
+	lun.LUNInitiatorList.LUNInitInfo = append(lun.LUNInitiatorList.LUNInitInfo, struct {
+		InitiatorIndex int    `xml:"initiatorIndex"`
+		InitiatorIQN   string `xml:"initiatorIQN"`
+		AccessMode     int    `xml:"accessMode"`
+	}{InitiatorIndex: initiatorIndex, InitiatorIQN: iqn, AccessMode: accessMode})
+
+	return &response{Result: "0"}
+}
+
+func (s *Server) unassignLUN(lunIndex, targetIndex int) *response {
+	lun, ok := s.luns[lunIndex]
+	if !ok || lun.LUNTargetList.SingleRow == nil || lun.LUNTargetList.SingleRow.TargetIndex != targetIndex {
+		return &response{Result: "-1"}
+	}
+
+	lun.LUNTargetList.SingleRow = nil
+	lun.LUNNumber = 0
+	lun.LUNAttachedTarget = 0
+
+	return &response{Result: "0"}
+}
+
+func (s *Server) createTarget(name, alias string) *response {
+	for _, target := range s.targets {
+		if target.TargetName == name {
+			return &response{Result: "-1"}
+		}
+	}
+
+	index := s.nextTarget
+	s.nextTarget++
+
+	s.targets[index] = &manager.ISCSITarget{
+		TargetIndex:  index,
+		TargetName:   name,
+		TargetAlias:  alias,
+		TargetIQN:    fmt.Sprintf("iqn.2004-04.com.qnap:ts-fake:iscsi.%v.%06x", strings.ToLower(name), index),
+		TargetStatus: 1,
+	}
+
+	return &response{Result: strconv.Itoa(index)}
+}
+
+func (s *Server) deleteTarget(targetIndex int) *response {
+	if _, ok := s.targets[targetIndex]; !ok {
+		return &response{Result: "-1"}
+	}
+
+	// the LUNs are kept, but unassigned
+	for _, lun := range s.luns {
+		if row := lun.LUNTargetList.SingleRow; row != nil && row.TargetIndex == targetIndex {
+			s.unassignLUN(lun.LUNIndex, targetIndex)
+		}
+	}
+
+	delete(s.targets, targetIndex)
+
+	return &response{Result: "0"}
+}
+
+func atoi(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
@@ -0,0 +1,72 @@
+package qnapfake
+
+import (
+	"testing"
+	"time"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+)
+
+func TestServer(t *testing.T) {
+	fake := NewServer("admin", "s3cr3t")
+	defer fake.Close()
+
+	s, err := manager.Connect(fake.URL, "admin", "s3cr3t", &manager.ConfigOptions{APICallTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer s.Close()
+
+	pools, err := s.GetStoragePools()
+	if err != nil {
+		t.Fatalf("Failed to retrieve storage pools: %v", err)
+	}
+	if len(pools) != 1 || pools[0].CapacityBytes != 1<<40 {
+		t.Fatalf("Wrong storage pools: %+v", pools)
+	}
+
+	target, err := s.CreateISCSITarget("test", "")
+	if err != nil {
+		t.Fatalf("Failed to create iSCSI target: %v", err)
+	}
+	if target.TargetName != "test" || target.TargetIQN == "" {
+		t.Fatalf("Wrong iSCSI target: %+v", target)
+	}
+
+	lun, err := s.CreateBlockBasedLUN(1, "data01", 10, manager.LUNAllocateMode_Thin, false, 80)
+	if err != nil {
+		t.Fatalf("Failed to create LUN: %v", err)
+	}
+	if lun.LUNName != "data01" || lun.CapacityBytes != 10<<30 || !lun.LUNThinAllocate {
+		t.Fatalf("Wrong LUN: %+v", lun)
+	}
+
+	if err := s.AssignLUN(lun.LUNIndex, target.TargetIndex); err != nil {
+		t.Fatalf("Failed to assign LUN: %v", err)
+	}
+	if lun, _ = s.GetLUNByIndex(lun.LUNIndex); lun.LUNTargetList.SingleRow == nil || lun.LUNTargetList.SingleRow.TargetIndex != target.TargetIndex {
+		t.Fatalf("LUN not assigned: %+v", lun)
+	}
+
+	if err := s.UnassignLUN(lun.LUNIndex, target.TargetIndex); err != nil {
+		t.Fatalf("Failed to unassign LUN: %v", err)
+	}
+	if err := s.DeleteISCSITarget(target.TargetIndex); err != nil {
+		t.Fatalf("Failed to delete iSCSI target: %v", err)
+	}
+	if err := s.DeleteLUN(lun.LUNIndex); err != nil {
+		t.Fatalf("Failed to delete LUN: %v", err)
+	}
+	if len(fake.LUNs()) != 0 || len(fake.ISCSITargets()) != 0 {
+		t.Fatalf("Fake not empty: %v LUNs, %v targets", len(fake.LUNs()), len(fake.ISCSITargets()))
+	}
+}
+
+func TestServer_InvalidLogin(t *testing.T) {
+	fake := NewServer("admin", "s3cr3t")
+	defer fake.Close()
+
+	if _, err := manager.Connect(fake.URL, "admin", "wrong", nil); err == nil {
+		t.Fatal("Error expected")
+	}
+}
@@ -0,0 +1,652 @@
+// Package qnapmock provides an in-memory mock of the QNAP API client for unit tests.
+//
+// Every call is recorded under its own method name and answered by the corresponding
+// function field, if set. The context variants record the context as first argument
+// and fall back to the function of their counterpart without context, the same applies
+// to the Ex variants. Unset functions return empty results without error.
+//
+// The accessors of the session state, like IsAdmin() or Username(), are answered by
+// their function fields as well, but are not recorded.
+package qnapmock
+
+import (
+	"context"
+	"sync"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+)
+
+// Call is a recorded call of the mock.
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// Client is a mock implementing the manager.Client interface.
+type Client struct {
+	LoginFunc                 func(username, password string) error
+	LoginWithOTPFunc          func(username, password, securityCode string) error
+	LoginWithTokenFunc        func(username, token string) error
+	LogoutFunc                func() error
+	CloseFunc                 func() error
+	GetSystemInfoFunc         func() (*manager.SystemInfo, error)
+	GetStoragePoolsFunc       func() ([]*manager.StoragePool, error)
+	GetStoragePoolsExFunc     func(options *manager.StoragePoolOptions) ([]*manager.StoragePool, error)
+	GetStoragePoolIDsFunc     func() ([]int, error)
+	GetLUNsFunc               func() ([]*manager.LUN, error)
+	GetLUNByIndexFunc         func(lunIndex int) (*manager.LUN, error)
+	GetISCSITargetsFunc       func() ([]*manager.ISCSITarget, error)
+	CreateBlockBasedLUNFunc   func(storagePoolID int, name string, capacityGB int, allocateMode manager.LUNAllocateMode, useSSDCache bool, alertThresoldPercent int) (*manager.LUN, error)
+	CreateBlockBasedLUNExFunc func(storagePoolID int, name string, capacityGB int, allocateMode manager.LUNAllocateMode, useSSDCache bool, alertThresoldPercent int, zfsOptions *manager.ZFSLUNOptions) (*manager.LUN, error)
+	DeleteLUNFunc             func(lunID int) error
+	WaitForLUNVolumeFunc      func(lunID int) (*manager.LUN, error)
+	AssignLUNFunc             func(lunIndex int, targetIndex int) error
+	UnassignLUNFunc           func(lunIndex int, targetIndex int) error
+	AddLUNInitiatorFunc       func(lunIndex int, initiatorIQN string, accessMode int) error
+	CreateISCSITargetFunc     func(name, alias string) (*manager.ISCSITarget, error)
+	DeleteISCSITargetFunc     func(targetIndex int) error
+	ExportISCSIConfigFunc     func() (*manager.ISCSIConfig, error)
+	ImportISCSIConfigFunc     func(config *manager.ISCSIConfig, options *manager.ImportOptions) (*manager.ImportResult, error)
+	InvalidateCacheFunc       func()
+
+	GetSystemInfoContextFunc       func(ctx context.Context) (*manager.SystemInfo, error)
+	GetStoragePoolsContextFunc     func(ctx context.Context) ([]*manager.StoragePool, error)
+	GetStoragePoolsExContextFunc   func(ctx context.Context, options *manager.StoragePoolOptions) ([]*manager.StoragePool, error)
+	GetStoragePoolIDsContextFunc   func(ctx context.Context) ([]int, error)
+	GetLUNsContextFunc             func(ctx context.Context) ([]*manager.LUN, error)
+	GetLUNByIndexContextFunc       func(ctx context.Context, lunIndex int) (*manager.LUN, error)
+	GetISCSITargetsContextFunc     func(ctx context.Context) ([]*manager.ISCSITarget, error)
+	CreateBlockBasedLUNContextFunc func(ctx context.Context, storagePoolID int, name string, capacityGB int, allocateMode manager.LUNAllocateMode, useSSDCache bool, alertThresoldPercent int, zfsOptions *manager.ZFSLUNOptions) (*manager.LUN, error)
+	DeleteLUNContextFunc           func(ctx context.Context, lunID int) error
+	WaitForLUNVolumeContextFunc    func(ctx context.Context, lunID int) (*manager.LUN, error)
+	AssignLUNContextFunc           func(ctx context.Context, lunIndex int, targetIndex int) error
+	UnassignLUNContextFunc         func(ctx context.Context, lunIndex int, targetIndex int) error
+	AddLUNInitiatorContextFunc     func(ctx context.Context, lunIndex int, initiatorIQN string, accessMode int) error
+	CreateISCSITargetContextFunc   func(ctx context.Context, name, alias string) (*manager.ISCSITarget, error)
+	DeleteISCSITargetContextFunc   func(ctx context.Context, targetIndex int) error
+	ExportISCSIConfigContextFunc   func(ctx context.Context) (*manager.ISCSIConfig, error)
+	ImportISCSIConfigContextFunc   func(ctx context.Context, config *manager.ISCSIConfig, options *manager.ImportOptions) (*manager.ImportResult, error)
+
+	IsAdminFunc      func() bool
+	UsernameFunc     func() string
+	TokenFunc        func() string
+	SessionIDFunc    func() string
+	NASHostnameFunc  func() string
+	SystemInfoFunc   func() *manager.SystemInfo
+	CapabilitiesFunc func() manager.Capabilities
+
+	lock  sync.Mutex
+	calls []Call
+}
+
+var _ manager.Client = (*Client)(nil)
+
+// Calls returns all recorded calls.
+func (c *Client) Calls() []Call {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return append([]Call(nil), c.calls...)
+}
+
+// CallsOf returns the recorded calls of the method.
+func (c *Client) CallsOf(method string) []Call {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var result []Call
+
+	for _, call := range c.calls {
+		if call.Method == method {
+			result = append(result, call)
+		}
+	}
+
+	return result
+}
+
+// Reset removes all recorded calls.
+func (c *Client) Reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.calls = nil
+}
+
+func (c *Client) record(method string, args ...interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.calls = append(c.calls, Call{Method: method, Args: args})
+}
+
+// Login records the call.
+func (c *Client) Login(username, password string) error {
+	c.record("Login", username, password)
+
+	if c.LoginFunc != nil {
+		return c.LoginFunc(username, password)
+	}
+	return nil
+}
+
+// LoginWithOTP records the call.
+func (c *Client) LoginWithOTP(username, password, securityCode string) error {
+	c.record("LoginWithOTP", username, password, securityCode)
+
+	if c.LoginWithOTPFunc != nil {
+		return c.LoginWithOTPFunc(username, password, securityCode)
+	}
+	return nil
+}
+
+// LoginWithToken records the call.
+func (c *Client) LoginWithToken(username, token string) error {
+	c.record("LoginWithToken", username, token)
+
+	if c.LoginWithTokenFunc != nil {
+		return c.LoginWithTokenFunc(username, token)
+	}
+	return nil
+}
+
+// Logout records the call.
+func (c *Client) Logout() error {
+	c.record("Logout")
+
+	if c.LogoutFunc != nil {
+		return c.LogoutFunc()
+	}
+	return nil
+}
+
+// Close records the call.
+func (c *Client) Close() error {
+	c.record("Close")
+
+	if c.CloseFunc != nil {
+		return c.CloseFunc()
+	}
+	return nil
+}
+
+// GetSystemInfo records the call.
+func (c *Client) GetSystemInfo() (*manager.SystemInfo, error) {
+	c.record("GetSystemInfo")
+
+	if c.GetSystemInfoFunc != nil {
+		return c.GetSystemInfoFunc()
+	}
+	return &manager.SystemInfo{}, nil
+}
+
+// GetStoragePools records the call.
+func (c *Client) GetStoragePools() ([]*manager.StoragePool, error) {
+	c.record("GetStoragePools")
+
+	if c.GetStoragePoolsFunc != nil {
+		return c.GetStoragePoolsFunc()
+	}
+	return []*manager.StoragePool{}, nil
+}
+
+// GetStoragePoolsEx records the call.
+func (c *Client) GetStoragePoolsEx(options *manager.StoragePoolOptions) ([]*manager.StoragePool, error) {
+	c.record("GetStoragePoolsEx", options)
+
+	if c.GetStoragePoolsExFunc != nil {
+		return c.GetStoragePoolsExFunc(options)
+	}
+	if c.GetStoragePoolsFunc != nil {
+		return c.GetStoragePoolsFunc()
+	}
+	return []*manager.StoragePool{}, nil
+}
+
+// GetStoragePoolIDs records the call.
+func (c *Client) GetStoragePoolIDs() ([]int, error) {
+	c.record("GetStoragePoolIDs")
+
+	if c.GetStoragePoolIDsFunc != nil {
+		return c.GetStoragePoolIDsFunc()
+	}
+	return []int{}, nil
+}
+
+// GetLUNs records the call.
+func (c *Client) GetLUNs() ([]*manager.LUN, error) {
+	c.record("GetLUNs")
+
+	if c.GetLUNsFunc != nil {
+		return c.GetLUNsFunc()
+	}
+	return []*manager.LUN{}, nil
+}
+
+// GetLUNByIndex records the call.
+func (c *Client) GetLUNByIndex(lunIndex int) (*manager.LUN, error) {
+	c.record("GetLUNByIndex", lunIndex)
+
+	if c.GetLUNByIndexFunc != nil {
+		return c.GetLUNByIndexFunc(lunIndex)
+	}
+	return nil, nil
+}
+
+// GetISCSITargets records the call.
+func (c *Client) GetISCSITargets() ([]*manager.ISCSITarget, error) {
+	c.record("GetISCSITargets")
+
+	if c.GetISCSITargetsFunc != nil {
+		return c.GetISCSITargetsFunc()
+	}
+	return []*manager.ISCSITarget{}, nil
+}
+
+// CreateBlockBasedLUN records the call.
+func (c *Client) CreateBlockBasedLUN(storagePoolID int, name string, capacityGB int, allocateMode manager.LUNAllocateMode, useSSDCache bool, alertThresoldPercent int) (*manager.LUN, error) {
+	c.record("CreateBlockBasedLUN", storagePoolID, name, capacityGB, allocateMode, useSSDCache, alertThresoldPercent)
+
+	if c.CreateBlockBasedLUNFunc != nil {
+		return c.CreateBlockBasedLUNFunc(storagePoolID, name, capacityGB, allocateMode, useSSDCache, alertThresoldPercent)
+	}
+	if c.CreateBlockBasedLUNExFunc != nil {
+		return c.CreateBlockBasedLUNExFunc(storagePoolID, name, capacityGB, allocateMode, useSSDCache, alertThresoldPercent, nil)
+	}
+	return &manager.LUN{LUNName: name, LUNPath: name, PoolID: storagePoolID, CapacityBytes: int64(capacityGB) << 30, VolumeID: -1}, nil
+}
+
+// CreateBlockBasedLUNEx records the call.
+func (c *Client) CreateBlockBasedLUNEx(storagePoolID int, name string, capacityGB int, allocateMode manager.LUNAllocateMode, useSSDCache bool, alertThresoldPercent int, zfsOptions *manager.ZFSLUNOptions) (*manager.LUN, error) {
+	c.record("CreateBlockBasedLUNEx", storagePoolID, name, capacityGB, allocateMode, useSSDCache, alertThresoldPercent, zfsOptions)
+
+	if c.CreateBlockBasedLUNExFunc != nil {
+		return c.CreateBlockBasedLUNExFunc(storagePoolID, name, capacityGB, allocateMode, useSSDCache, alertThresoldPercent, zfsOptions)
+	}
+	if c.CreateBlockBasedLUNFunc != nil {
+		return c.CreateBlockBasedLUNFunc(storagePoolID, name, capacityGB, allocateMode, useSSDCache, alertThresoldPercent)
+	}
+	return &manager.LUN{LUNName: name, LUNPath: name, PoolID: storagePoolID, CapacityBytes: int64(capacityGB) << 30, VolumeID: -1}, nil
+}
+
+// DeleteLUN records the call.
+func (c *Client) DeleteLUN(lunID int) error {
+	c.record("DeleteLUN", lunID)
+
+	if c.DeleteLUNFunc != nil {
+		return c.DeleteLUNFunc(lunID)
+	}
+	return nil
+}
+
+// WaitForLUNVolume records the call.
+func (c *Client) WaitForLUNVolume(lunID int) (*manager.LUN, error) {
+	c.record("WaitForLUNVolume", lunID)
+
+	if c.WaitForLUNVolumeFunc != nil {
+		return c.WaitForLUNVolumeFunc(lunID)
+	}
+	return &manager.LUN{LUNIndex: lunID}, nil
+}
+
+// AssignLUN records the call.
+func (c *Client) AssignLUN(lunIndex int, targetIndex int) error {
+	c.record("AssignLUN", lunIndex, targetIndex)
+
+	if c.AssignLUNFunc != nil {
+		return c.AssignLUNFunc(lunIndex, targetIndex)
+	}
+	return nil
+}
+
+// UnassignLUN records the call.
+func (c *Client) UnassignLUN(lunIndex int, targetIndex int) error {
+	c.record("UnassignLUN", lunIndex, targetIndex)
+
+	if c.UnassignLUNFunc != nil {
+		return c.UnassignLUNFunc(lunIndex, targetIndex)
+	}
+	return nil
+}
+
+// AddLUNInitiator records the call.
+func (c *Client) AddLUNInitiator(lunIndex int, initiatorIQN string, accessMode int) error {
+	c.record("AddLUNInitiator", lunIndex, initiatorIQN, accessMode)
+
+	if c.AddLUNInitiatorFunc != nil {
+		return c.AddLUNInitiatorFunc(lunIndex, initiatorIQN, accessMode)
+	}
+	return nil
+}
+
+// CreateISCSITarget records the call.
+func (c *Client) CreateISCSITarget(name, alias string) (*manager.ISCSITarget, error) {
+	c.record("CreateISCSITarget", name, alias)
+
+	if c.CreateISCSITargetFunc != nil {
+		return c.CreateISCSITargetFunc(name, alias)
+	}
+	return &manager.ISCSITarget{TargetName: name, TargetAlias: alias}, nil
+}
+
+// DeleteISCSITarget records the call.
+func (c *Client) DeleteISCSITarget(targetIndex int) error {
+	c.record("DeleteISCSITarget", targetIndex)
+
+	if c.DeleteISCSITargetFunc != nil {
+		return c.DeleteISCSITargetFunc(targetIndex)
+	}
+	return nil
+}
+
+// ExportISCSIConfig records the call.
+func (c *Client) ExportISCSIConfig() (*manager.ISCSIConfig, error) {
+	c.record("ExportISCSIConfig")
+
+	if c.ExportISCSIConfigFunc != nil {
+		return c.ExportISCSIConfigFunc()
+	}
+	return &manager.ISCSIConfig{Version: manager.ISCSIConfigVersion}, nil
+}
+
+// ImportISCSIConfig records the call.
+func (c *Client) ImportISCSIConfig(config *manager.ISCSIConfig, options *manager.ImportOptions) (*manager.ImportResult, error) {
+	c.record("ImportISCSIConfig", config, options)
+
+	if c.ImportISCSIConfigFunc != nil {
+		return c.ImportISCSIConfigFunc(config, options)
+	}
+	return &manager.ImportResult{Targets: map[int]int{}, LUNs: map[int]int{}}, nil
+}
+
+// InvalidateCache records the call.
+func (c *Client) InvalidateCache() {
+	c.record("InvalidateCache")
+
+	if c.InvalidateCacheFunc != nil {
+		c.InvalidateCacheFunc()
+	}
+}
+
+// GetSystemInfoContext records the call, including the context.
+func (c *Client) GetSystemInfoContext(ctx context.Context) (*manager.SystemInfo, error) {
+	c.record("GetSystemInfoContext", ctx)
+
+	if c.GetSystemInfoContextFunc != nil {
+		return c.GetSystemInfoContextFunc(ctx)
+	}
+	if c.GetSystemInfoFunc != nil {
+		return c.GetSystemInfoFunc()
+	}
+	return &manager.SystemInfo{}, nil
+}
+
+// GetStoragePoolsContext records the call, including the context.
+func (c *Client) GetStoragePoolsContext(ctx context.Context) ([]*manager.StoragePool, error) {
+	c.record("GetStoragePoolsContext", ctx)
+
+	if c.GetStoragePoolsContextFunc != nil {
+		return c.GetStoragePoolsContextFunc(ctx)
+	}
+	if c.GetStoragePoolsFunc != nil {
+		return c.GetStoragePoolsFunc()
+	}
+	return []*manager.StoragePool{}, nil
+}
+
+// GetStoragePoolsExContext records the call, including the context.
+func (c *Client) GetStoragePoolsExContext(ctx context.Context, options *manager.StoragePoolOptions) ([]*manager.StoragePool, error) {
+	c.record("GetStoragePoolsExContext", ctx, options)
+
+	if c.GetStoragePoolsExContextFunc != nil {
+		return c.GetStoragePoolsExContextFunc(ctx, options)
+	}
+	if c.GetStoragePoolsExFunc != nil {
+		return c.GetStoragePoolsExFunc(options)
+	}
+	if c.GetStoragePoolsFunc != nil {
+		return c.GetStoragePoolsFunc()
+	}
+	return []*manager.StoragePool{}, nil
+}
+
+// GetStoragePoolIDsContext records the call, including the context.
+func (c *Client) GetStoragePoolIDsContext(ctx context.Context) ([]int, error) {
+	c.record("GetStoragePoolIDsContext", ctx)
+
+	if c.GetStoragePoolIDsContextFunc != nil {
+		return c.GetStoragePoolIDsContextFunc(ctx)
+	}
+	if c.GetStoragePoolIDsFunc != nil {
+		return c.GetStoragePoolIDsFunc()
+	}
+	return []int{}, nil
+}
+
+// GetLUNsContext records the call, including the context.
+func (c *Client) GetLUNsContext(ctx context.Context) ([]*manager.LUN, error) {
+	c.record("GetLUNsContext", ctx)
+
+	if c.GetLUNsContextFunc != nil {
+		return c.GetLUNsContextFunc(ctx)
+	}
+	if c.GetLUNsFunc != nil {
+		return c.GetLUNsFunc()
+	}
+	return []*manager.LUN{}, nil
+}
+
+// GetLUNByIndexContext records the call, including the context.
+func (c *Client) GetLUNByIndexContext(ctx context.Context, lunIndex int) (*manager.LUN, error) {
+	c.record("GetLUNByIndexContext", ctx, lunIndex)
+
+	if c.GetLUNByIndexContextFunc != nil {
+		return c.GetLUNByIndexContextFunc(ctx, lunIndex)
+	}
+	if c.GetLUNByIndexFunc != nil {
+		return c.GetLUNByIndexFunc(lunIndex)
+	}
+	return nil, nil
+}
+
+// GetISCSITargetsContext records the call, including the context.
+func (c *Client) GetISCSITargetsContext(ctx context.Context) ([]*manager.ISCSITarget, error) {
+	c.record("GetISCSITargetsContext", ctx)
+
+	if c.GetISCSITargetsContextFunc != nil {
+		return c.GetISCSITargetsContextFunc(ctx)
+	}
+	if c.GetISCSITargetsFunc != nil {
+		return c.GetISCSITargetsFunc()
+	}
+	return []*manager.ISCSITarget{}, nil
+}
+
+// CreateBlockBasedLUNContext records the call, including the context.
+func (c *Client) CreateBlockBasedLUNContext(ctx context.Context, storagePoolID int, name string, capacityGB int, allocateMode manager.LUNAllocateMode, useSSDCache bool, alertThresoldPercent int, zfsOptions *manager.ZFSLUNOptions) (*manager.LUN, error) {
+	c.record("CreateBlockBasedLUNContext", ctx, storagePoolID, name, capacityGB, allocateMode, useSSDCache, alertThresoldPercent, zfsOptions)
+
+	if c.CreateBlockBasedLUNContextFunc != nil {
+		return c.CreateBlockBasedLUNContextFunc(ctx, storagePoolID, name, capacityGB, allocateMode, useSSDCache, alertThresoldPercent, zfsOptions)
+	}
+	if c.CreateBlockBasedLUNExFunc != nil {
+		return c.CreateBlockBasedLUNExFunc(storagePoolID, name, capacityGB, allocateMode, useSSDCache, alertThresoldPercent, zfsOptions)
+	}
+	if c.CreateBlockBasedLUNFunc != nil {
+		return c.CreateBlockBasedLUNFunc(storagePoolID, name, capacityGB, allocateMode, useSSDCache, alertThresoldPercent)
+	}
+	return &manager.LUN{LUNName: name, LUNPath: name, PoolID: storagePoolID, CapacityBytes: int64(capacityGB) << 30, VolumeID: -1}, nil
+}
+
+// DeleteLUNContext records the call, including the context.
+func (c *Client) DeleteLUNContext(ctx context.Context, lunID int) error {
+	c.record("DeleteLUNContext", ctx, lunID)
+
+	if c.DeleteLUNContextFunc != nil {
+		return c.DeleteLUNContextFunc(ctx, lunID)
+	}
+	if c.DeleteLUNFunc != nil {
+		return c.DeleteLUNFunc(lunID)
+	}
+	return nil
+}
+
+// WaitForLUNVolumeContext records the call, including the context.
+func (c *Client) WaitForLUNVolumeContext(ctx context.Context, lunID int) (*manager.LUN, error) {
+	c.record("WaitForLUNVolumeContext", ctx, lunID)
+
+	if c.WaitForLUNVolumeContextFunc != nil {
+		return c.WaitForLUNVolumeContextFunc(ctx, lunID)
+	}
+	if c.WaitForLUNVolumeFunc != nil {
+		return c.WaitForLUNVolumeFunc(lunID)
+	}
+	return &manager.LUN{LUNIndex: lunID}, nil
+}
+
+// AssignLUNContext records the call, including the context.
+func (c *Client) AssignLUNContext(ctx context.Context, lunIndex int, targetIndex int) error {
+	c.record("AssignLUNContext", ctx, lunIndex, targetIndex)
+
+	if c.AssignLUNContextFunc != nil {
+		return c.AssignLUNContextFunc(ctx, lunIndex, targetIndex)
+	}
+	if c.AssignLUNFunc != nil {
+		return c.AssignLUNFunc(lunIndex, targetIndex)
+	}
+	return nil
+}
+
+// UnassignLUNContext records the call, including the context.
+func (c *Client) UnassignLUNContext(ctx context.Context, lunIndex int, targetIndex int) error {
+	c.record("UnassignLUNContext", ctx, lunIndex, targetIndex)
+
+	if c.UnassignLUNContextFunc != nil {
+		return c.UnassignLUNContextFunc(ctx, lunIndex, targetIndex)
+	}
+	if c.UnassignLUNFunc != nil {
+		return c.UnassignLUNFunc(lunIndex, targetIndex)
+	}
+	return nil
+}
+
+// AddLUNInitiatorContext records the call, including the context.
+func (c *Client) AddLUNInitiatorContext(ctx context.Context, lunIndex int, initiatorIQN string, accessMode int) error {
+	c.record("AddLUNInitiatorContext", ctx, lunIndex, initiatorIQN, accessMode)
+
+	if c.AddLUNInitiatorContextFunc != nil {
+		return c.AddLUNInitiatorContextFunc(ctx, lunIndex, initiatorIQN, accessMode)
+	}
+	if c.AddLUNInitiatorFunc != nil {
+		return c.AddLUNInitiatorFunc(lunIndex, initiatorIQN, accessMode)
+	}
+	return nil
+}
+
+// CreateISCSITargetContext records the call, including the context.
+func (c *Client) CreateISCSITargetContext(ctx context.Context, name, alias string) (*manager.ISCSITarget, error) {
+	c.record("CreateISCSITargetContext", ctx, name, alias)
+
+	if c.CreateISCSITargetContextFunc != nil {
+		return c.CreateISCSITargetContextFunc(ctx, name, alias)
+	}
+	if c.CreateISCSITargetFunc != nil {
+		return c.CreateISCSITargetFunc(name, alias)
+	}
+	return &manager.ISCSITarget{TargetName: name, TargetAlias: alias}, nil
+}
+
+// DeleteISCSITargetContext records the call, including the context.
+func (c *Client) DeleteISCSITargetContext(ctx context.Context, targetIndex int) error {
+	c.record("DeleteISCSITargetContext", ctx, targetIndex)
+
+	if c.DeleteISCSITargetContextFunc != nil {
+		return c.DeleteISCSITargetContextFunc(ctx, targetIndex)
+	}
+	if c.DeleteISCSITargetFunc != nil {
+		return c.DeleteISCSITargetFunc(targetIndex)
+	}
+	return nil
+}
+
+// ExportISCSIConfigContext records the call, including the context.
+func (c *Client) ExportISCSIConfigContext(ctx context.Context) (*manager.ISCSIConfig, error) {
+	c.record("ExportISCSIConfigContext", ctx)
+
+	if c.ExportISCSIConfigContextFunc != nil {
+		return c.ExportISCSIConfigContextFunc(ctx)
+	}
+	if c.ExportISCSIConfigFunc != nil {
+		return c.ExportISCSIConfigFunc()
+	}
+	return &manager.ISCSIConfig{Version: manager.ISCSIConfigVersion}, nil
+}
+
+// ImportISCSIConfigContext records the call, including the context.
+func (c *Client) ImportISCSIConfigContext(ctx context.Context, config *manager.ISCSIConfig, options *manager.ImportOptions) (*manager.ImportResult, error) {
+	c.record("ImportISCSIConfigContext", ctx, config, options)
+
+	if c.ImportISCSIConfigContextFunc != nil {
+		return c.ImportISCSIConfigContextFunc(ctx, config, options)
+	}
+	if c.ImportISCSIConfigFunc != nil {
+		return c.ImportISCSIConfigFunc(config, options)
+	}
+	return &manager.ImportResult{Targets: map[int]int{}, LUNs: map[int]int{}}, nil
+}
+
+// IsAdmin returns the result of IsAdminFunc, the zero value if unset.
+func (c *Client) IsAdmin() bool {
+	if c.IsAdminFunc != nil {
+		return c.IsAdminFunc()
+	}
+	return false
+}
+
+// Username returns the result of UsernameFunc, the zero value if unset.
+func (c *Client) Username() string {
+	if c.UsernameFunc != nil {
+		return c.UsernameFunc()
+	}
+	return ""
+}
+
+// Token returns the result of TokenFunc, the zero value if unset.
+func (c *Client) Token() string {
+	if c.TokenFunc != nil {
+		return c.TokenFunc()
+	}
+	return ""
+}
+
+// SessionID returns the result of SessionIDFunc, the zero value if unset.
+func (c *Client) SessionID() string {
+	if c.SessionIDFunc != nil {
+		return c.SessionIDFunc()
+	}
+	return ""
+}
+
+// NASHostname returns the result of NASHostnameFunc, the zero value if unset.
+func (c *Client) NASHostname() string {
+	if c.NASHostnameFunc != nil {
+		return c.NASHostnameFunc()
+	}
+	return ""
+}
+
+// SystemInfo returns the result of SystemInfoFunc, the zero value if unset.
+func (c *Client) SystemInfo() *manager.SystemInfo {
+	if c.SystemInfoFunc != nil {
+		return c.SystemInfoFunc()
+	}
+	return nil
+}
+
+// Capabilities returns the result of CapabilitiesFunc, the zero value if unset.
+func (c *Client) Capabilities() manager.Capabilities {
+	if c.CapabilitiesFunc != nil {
+		return c.CapabilitiesFunc()
+	}
+	return manager.Capabilities{}
+}
@@ -0,0 +1,89 @@
+package qnapmock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+)
+
+func TestClient(t *testing.T) {
+	expectedErr := errors.New("scripted error")
+
+	var client manager.Client = &Client{
+		DeleteLUNFunc: func(lunID int) error {
+			return expectedErr
+		},
+	}
+
+	if _, err := client.GetLUNs(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := client.DeleteLUN(42); err != expectedErr {
+		t.Fatalf("Scripted error expected: %v", err)
+	}
+
+	mock := client.(*Client)
+
+	if len(mock.Calls()) != 2 {
+		t.Fatalf("Expected 2 recorded calls, got %v", len(mock.Calls()))
+	}
+
+	calls := mock.CallsOf("DeleteLUN")
+	if len(calls) != 1 || calls[0].Args[0] != 42 {
+		t.Fatalf("Wrong recorded DeleteLUN calls: %+v", calls)
+	}
+}
+
+func TestClient_ContextVariants(t *testing.T) {
+	var deleted []int
+
+	client := &Client{
+		DeleteLUNFunc: func(lunID int) error {
+			deleted = append(deleted, lunID)
+			return nil
+		},
+		CreateBlockBasedLUNContextFunc: func(ctx context.Context, storagePoolID int, name string, capacityGB int, allocateMode manager.LUNAllocateMode, useSSDCache bool, alertThresoldPercent int, zfsOptions *manager.ZFSLUNOptions) (*manager.LUN, error) {
+			return &manager.LUN{LUNName: "context"}, nil
+		},
+		IsAdminFunc:     func() bool { return true },
+		NASHostnameFunc: func() string { return "nas01" },
+	}
+
+	ctx := context.Background()
+
+	// falls back to the function without context
+	if err := client.DeleteLUNContext(ctx, 42); err != nil || len(deleted) != 1 || deleted[0] != 42 {
+		t.Fatalf("Fallback to DeleteLUNFunc expected: %v, %v", err, deleted)
+	}
+
+	// the variant without context does not use the context function
+	lun, err := client.CreateBlockBasedLUN(1, "plain", 10, manager.LUNAllocateMode_Thin, false, 80)
+	if err != nil || lun.LUNName != "plain" {
+		t.Fatalf("Default result expected: %+v, %v", lun, err)
+	}
+	lun, err = client.CreateBlockBasedLUNContext(ctx, 1, "plain", 10, manager.LUNAllocateMode_Thin, false, 80, nil)
+	if err != nil || lun.LUNName != "context" {
+		t.Fatalf("Scripted result expected: %+v, %v", lun, err)
+	}
+
+	if !client.IsAdmin() || client.Username() != "" || client.NASHostname() != "nas01" || client.SessionID() != "" {
+		t.Fatal("Wrong accessor results")
+	}
+
+	client.InvalidateCache()
+
+	// every method is recorded under its own name, accessors are not recorded
+	var methods []string
+	for _, call := range client.Calls() {
+		methods = append(methods, call.Method)
+	}
+	if fmt.Sprint(methods) != "[DeleteLUNContext CreateBlockBasedLUN CreateBlockBasedLUNContext InvalidateCache]" {
+		t.Fatalf("Wrong recorded calls: %v", methods)
+	}
+	if calls := client.CallsOf("DeleteLUNContext"); calls[0].Args[0] != ctx || calls[0].Args[1] != 42 {
+		t.Fatalf("Wrong recorded arguments: %+v", calls)
+	}
+}
@@ -0,0 +1,197 @@
+package manager
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type authCheckResponse struct {
+	AuthPassed string `xml:"authPassed"`
+}
+
+// execute performs a reading API request on behalf of the session.
+// Transient errors are retried according to the retry policy.
+func (s *QnapSession) execute(req *resty.Request, method, path string) (*resty.Response, error) {
+	return s.executeWithRetry(req, method, path, true)
+}
+
+// executeMutation performs a mutating API request on behalf of the session.
+// Transient errors are only retried if the retry policy allows retrying mutations.
+// The caller must hold the mutation lock, see lockMutations().
+func (s *QnapSession) executeMutation(req *resty.Request, method, path string) (*resty.Response, error) {
+	policy := s.retryPolicy()
+
+	// the mutation might have succeeded even on error
+	defer s.cache.invalidate()
+
+	return s.executeWithRetry(req, method, path, policy != nil && policy.RetryMutations)
+}
+
+func (s *QnapSession) executeWithRetry(req *resty.Request, method, path string, retry bool) (*resty.Response, error) {
+	// the context of the operation, the parent of the spans of all attempts
+	ctx := req.Context()
+
+	policy := s.retryPolicy()
+	if !retry || policy == nil || policy.MaxAttempts <= 1 {
+		return s.executeAuthenticated(ctx, req, method, path)
+	}
+
+	backoff := policy.initialBackoff()
+
+	for attempt := 1; ; attempt++ {
+		res, err := s.executeAuthenticated(ctx, req, method, path)
+
+		statusCode := 0
+		var body []byte
+		if res != nil {
+			statusCode = res.StatusCode()
+			body = res.Body()
+		}
+
+		if attempt >= policy.MaxAttempts || !policy.retryable(statusCode, body, err) {
+			return res, err
+		}
+
+		reason := retryReason(statusCode, err)
+
+		s.log(slog.LevelDebug, "Retrying QNAP API request", "endpoint", path, "func", req.QueryParam.Get("func"), "attempt", attempt, "backoff", backoff, "reason", redact(reason.Error()))
+
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, backoff, reason)
+		}
+
+		if err := sleepContext(ctx, backoff); err != nil {
+			return nil, err
+		}
+		backoff = policy.nextBackoff(backoff)
+	}
+}
+
+// lockMutations serializes the mutating calls of all sessions sharing the state, e.g. of a session pool,
+// including the polling until their result is visible. The NAS misbehaves on concurrent changes.
+// It returns the function releasing the lock.
+func (s *QnapSession) lockMutations() func() {
+	if s.shared == nil {
+		return func() {}
+	}
+
+	s.shared.mutationLock.Lock()
+	return s.shared.mutationLock.Unlock
+}
+
+func (s *QnapSession) retryPolicy() *RetryPolicy {
+	if s.options == nil {
+		return nil
+	}
+	return s.options.RetryPolicy
+}
+
+// executeAuthenticated performs the API request on behalf of the session.
+// If the session has expired, it logs-in again once and repeats the request.
+func (s *QnapSession) executeAuthenticated(ctx context.Context, req *resty.Request, method, path string) (*resty.Response, error) {
+	sessionID := s.SessionID()
+
+	res, err := s.executeWithSessionID(ctx, req, method, path, sessionID)
+	if err != nil || !isSessionExpired(res) || s.credentials == nil {
+		return res, err
+	}
+
+	// session expired, login again
+	s.log(slog.LevelInfo, "QNAP session expired, logging in again", "host", s.host)
+
+	err = s.reloginExpired(ctx, sessionID)
+
+	if recorder := s.metricsRecorder(); recorder != nil {
+		recorder.ObserveRelogin(s.host, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to login again after session expired: %w", err)
+	}
+
+	return s.executeWithSessionID(ctx, req, method, path, s.SessionID())
+}
+
+// executeWithSessionID performs a single attempt of the API request, as child span of the operation's context.
+func (s *QnapSession) executeWithSessionID(ctx context.Context, req *resty.Request, method, path, sessionID string) (*resty.Response, error) {
+	req.SetFormData(map[string]string{"sid": sessionID}) // keep the session ID out of the URL
+
+	spanCtx, span := s.startSpan(ctx, "request",
+		attribute.String("qnap.endpoint", path),
+		attribute.String("qnap.func", req.QueryParam.Get("func")))
+
+	start := time.Now()
+
+	res, err := req.SetContext(spanCtx).Execute(method, path)
+
+	duration := time.Since(start)
+
+	// the span has ended, so never use it as parent of further attempts
+	req.SetContext(ctx)
+
+	if res != nil {
+		span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode()))
+	}
+	endSpan(span, err)
+
+	s.logRequest(req, method, path, duration, res, err)
+	s.recordRequest(req, path, duration, res, err)
+
+	return res, err
+}
+
+// loginWithProvider performs the login, retrieving the credentials from the session's provider.
+func (s *QnapSession) loginWithProvider(ctx context.Context) (err error) {
+	ctx, span := s.startSpan(ctx, "Login")
+	defer func() { endSpan(span, err) }()
+
+	s.loginLock.Lock()
+	defer s.loginLock.Unlock()
+
+	return s.loginWithProviderLocked(ctx)
+}
+
+// reloginExpired performs the login again, unless another goroutine
+// already replaced the expired session in the meantime.
+func (s *QnapSession) reloginExpired(ctx context.Context, expiredSessionID string) (err error) {
+	ctx, span := s.startSpan(ctx, "relogin")
+	defer func() { endSpan(span, err) }()
+
+	s.loginLock.Lock()
+	defer s.loginLock.Unlock()
+
+	if s.SessionID() != expiredSessionID {
+		return nil
+	}
+
+	return s.loginWithProviderLocked(ctx)
+}
+
+func (s *QnapSession) loginWithProviderLocked(ctx context.Context) error {
+	username, password, err := s.credentials.Credentials()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve credentials: %w", err)
+	}
+
+	return s.loginPassword(ctx, username, password)
+}
+
+// isSessionExpired checks whether the NAS rejected the request due to an invalid session.
+func isSessionExpired(res *resty.Response) bool {
+	if res.StatusCode() != 200 {
+		return false
+	}
+
+	var result authCheckResponse
+
+	if err := xml.Unmarshal(res.Body(), &result); err != nil {
+		return false
+	}
+
+	return result.AuthPassed == "0"
+}
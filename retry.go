@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy defines how failed API calls are retried, e.g. when a busy NAS
+// responds with HTTP 5xx, resets the connection or returns an empty response.
+//
+// Read calls are retried according to the policy, mutating calls (like
+// CreateBlockBasedLUN, DeleteLUN or AssignLUN) only if RetryMutations is enabled.
+type RetryPolicy struct {
+	MaxAttempts    int           // total number of attempts, including the first one
+	InitialBackoff time.Duration // wait time before the first retry, doubled on every further retry; defaults to one second
+	MaxBackoff     time.Duration // upper limit of the wait time, zero for no limit
+	RetryMutations bool          // also retry mutating calls, which might not be idempotent
+
+	// Retryable decides whether the attempt is retried, defaults to DefaultRetryable.
+	Retryable func(statusCode int, body []byte, err error) bool
+
+	// OnRetry is called before waiting for the next attempt.
+	OnRetry func(attempt int, backoff time.Duration, reason error)
+}
+
+// DefaultRetryPolicy retries up to three times, starting with one second of backoff.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 1 * time.Second,
+	MaxBackoff:     10 * time.Second,
+}
+
+// DefaultRetryable classifies HTTP 5xx responses, empty responses, timeouts
+// and connection resets as transient errors.
+func DefaultRetryable(statusCode int, body []byte, err error) bool {
+	if err != nil {
+		var netErr net.Error
+
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return true
+		}
+
+		return errors.Is(err, syscall.ECONNRESET) ||
+			errors.Is(err, syscall.ECONNREFUSED) ||
+			errors.Is(err, io.EOF) ||
+			errors.Is(err, io.ErrUnexpectedEOF)
+	}
+
+	if statusCode >= 500 {
+		return true
+	}
+
+	return statusCode == 200 && len(bytes.TrimSpace(body)) == 0
+}
+
+func (p *RetryPolicy) retryable(statusCode int, body []byte, err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(statusCode, body, err)
+	}
+	return DefaultRetryable(statusCode, body, err)
+}
+
+func (p *RetryPolicy) initialBackoff() time.Duration {
+	if p.InitialBackoff <= 0 {
+		return DefaultRetryPolicy.InitialBackoff
+	}
+	return p.InitialBackoff
+}
+
+func (p *RetryPolicy) nextBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff
+}
+
+// retryReason describes why the attempt failed, for the OnRetry hook.
+func retryReason(statusCode int, err error) error {
+	if err != nil {
+		return err
+	}
+	if statusCode != 200 {
+		return fmt.Errorf("unexpected HTTP status code: %v", statusCode)
+	}
+	return fmt.Errorf("empty response")
+}
@@ -0,0 +1,29 @@
+package manager
+
+import "testing"
+
+func TestDefaultRetryable(t *testing.T) {
+	if !DefaultRetryable(503, nil, nil) {
+		t.Fatal("HTTP 503 should be retryable")
+	}
+	if !DefaultRetryable(200, []byte("  "), nil) {
+		t.Fatal("Empty response should be retryable")
+	}
+	if DefaultRetryable(200, []byte("<QDocRoot></QDocRoot>"), nil) {
+		t.Fatal("Valid response should not be retryable")
+	}
+	if DefaultRetryable(404, nil, nil) {
+		t.Fatal("HTTP 404 should not be retryable")
+	}
+}
+
+func TestRetryPolicy_ZeroInitialBackoff(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 3}
+
+	if backoff := policy.initialBackoff(); backoff != DefaultRetryPolicy.InitialBackoff {
+		t.Fatalf("Default backoff expected, got %v", backoff)
+	}
+	if backoff := policy.nextBackoff(policy.initialBackoff()); backoff <= 0 {
+		t.Fatalf("Growing backoff expected, got %v", backoff)
+	}
+}
@@ -0,0 +1,142 @@
+package manager_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+	"github.com/nine-lives-later/go-qnap-disk-manager/qnapfake"
+)
+
+func TestSession_ReloginOnExpiredSession(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	var lock sync.Mutex
+	logins := 0
+
+	fake.SetInterceptor(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/cgi-bin/authLogin.cgi" {
+			lock.Lock()
+			logins++
+			lock.Unlock()
+		}
+		return false
+	})
+
+	s := connectFake(t, fake)
+	fake.ExpireSessions()
+
+	if _, err := s.GetISCSITargets(); err != nil {
+		t.Fatalf("Failed to retrieve iSCSI targets: %v", err)
+	}
+	if logins != 2 {
+		t.Fatalf("Expected exactly one re-login, got %v logins", logins)
+	}
+}
+
+func TestSession_ConcurrentMutationsSerialized(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	var meter concurrencyMeter
+
+	fake.SetInterceptor(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/cgi-bin/disk/iscsi_target_setting.cgi" {
+			meter.track(10 * time.Millisecond)
+		}
+		return false
+	})
+
+	s := connectFake(t, fake)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			// the LUNs do not exist, only the serialization matters
+			s.AssignLUN(i, 1)
+		}(i)
+	}
+
+	wg.Wait()
+
+	if meter.max() != 1 {
+		t.Fatalf("Mutating calls must be serialized, got %v concurrent calls", meter.max())
+	}
+}
+
+// failCalls lets the first API calls (besides login, logout and system information) fail with HTTP 503.
+func failCalls(fake *qnapfake.Server, failures int) *int {
+	var lock sync.Mutex
+	calls := 0
+
+	fake.SetInterceptor(func(w http.ResponseWriter, r *http.Request) bool {
+		switch r.URL.Path {
+		case "/cgi-bin/authLogin.cgi", "/cgi-bin/authLogout.cgi", "/cgi-bin/management/manaRequest.cgi":
+			return false
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		calls++
+		if calls <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return true
+		}
+		return false
+	})
+
+	return &calls
+}
+
+func TestSession_RetryReadCall(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	calls := failCalls(fake, 2)
+	retries := 0
+
+	s := connectFakeWithOptions(t, fake, &manager.ConfigOptions{
+		APICallTimeout: 5 * time.Second,
+		RetryPolicy: &manager.RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			OnRetry: func(attempt int, backoff time.Duration, reason error) {
+				retries++
+			},
+		},
+	})
+
+	if _, err := s.GetISCSITargets(); err != nil {
+		t.Fatalf("Failed to retrieve iSCSI targets: %v", err)
+	}
+	if *calls != 3 || retries != 2 {
+		t.Fatalf("Expected 3 calls and 2 retries, got %v calls and %v retries", *calls, retries)
+	}
+}
+
+func TestSession_RetryMutationNotRetriedByDefault(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	calls := failCalls(fake, 1)
+
+	s := connectFakeWithOptions(t, fake, &manager.ConfigOptions{
+		APICallTimeout: 5 * time.Second,
+		RetryPolicy:    &manager.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+	})
+
+	if err := s.AssignLUN(1, 1); err == nil {
+		t.Fatal("Error expected")
+	}
+	if *calls != 1 {
+		t.Fatalf("Mutation must not be retried, got %v calls", *calls)
+	}
+}
@@ -0,0 +1,199 @@
+package manager_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+	"github.com/nine-lives-later/go-qnap-disk-manager/qnapfake"
+)
+
+func TestSessionPool(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	var lock sync.Mutex
+	logins, logouts := 0, 0
+
+	fake.SetInterceptor(func(w http.ResponseWriter, r *http.Request) bool {
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch r.URL.Path {
+		case "/cgi-bin/authLogin.cgi":
+			logins++
+		case "/cgi-bin/authLogout.cgi":
+			logouts++
+		}
+		return false
+	})
+
+	pool, err := manager.NewSessionPool(fake.URL, manager.StaticCredentials{Username: "admin", Password: "admin"}, &manager.ConfigOptions{APICallTimeout: 5 * time.Second}, &manager.PoolOptions{Size: 3})
+	if err != nil {
+		t.Fatalf("Failed to create session pool: %v", err)
+	}
+	if logins != 3 {
+		t.Fatalf("Expected 3 logins, got %v", logins)
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if _, err := pool.GetISCSITargets(); err != nil {
+				t.Errorf("Failed to retrieve iSCSI targets: %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if err := pool.Close(); err != nil {
+		t.Fatalf("Failed to close session pool: %v", err)
+	}
+	if logouts != 3 {
+		t.Fatalf("Expected 3 logouts, got %v", logouts)
+	}
+
+	_, err = pool.GetISCSITargets()
+	if !errors.Is(err, manager.ErrPoolClosed) {
+		t.Fatalf("Pool closed error expected: %v", err)
+	}
+}
+
+func TestSessionPool_MutationsSerialized(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	var meter concurrencyMeter
+
+	fake.SetInterceptor(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/cgi-bin/disk/iscsi_target_setting.cgi" {
+			meter.track(10 * time.Millisecond)
+		}
+		return false
+	})
+
+	pool, err := manager.NewSessionPool(fake.URL, manager.StaticCredentials{Username: "admin", Password: "admin"}, &manager.ConfigOptions{APICallTimeout: 5 * time.Second}, &manager.PoolOptions{Size: 3})
+	if err != nil {
+		t.Fatalf("Failed to create session pool: %v", err)
+	}
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			// the LUNs do not exist, only the serialization matters
+			pool.Do(func(s *manager.QnapSession) error {
+				return s.AssignLUN(i, 1)
+			})
+		}(i)
+	}
+
+	wg.Wait()
+
+	if meter.max() != 1 {
+		t.Fatalf("Mutating calls of the pooled sessions must be serialized, got %v concurrent calls", meter.max())
+	}
+}
+
+func TestSessionPool_CloseWaitsForRunningCalls(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	pool, err := manager.NewSessionPool(fake.URL, manager.StaticCredentials{Username: "admin", Password: "admin"}, &manager.ConfigOptions{APICallTimeout: 5 * time.Second}, &manager.PoolOptions{Size: 1})
+	if err != nil {
+		t.Fatalf("Failed to create session pool: %v", err)
+	}
+
+	started := make(chan struct{})
+	done := make(chan error)
+
+	go func() {
+		done <- pool.Do(func(s *manager.QnapSession) error {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+
+			_, err := s.GetLUNs()
+			return err
+		})
+	}()
+
+	<-started
+
+	if err := pool.Close(); err != nil {
+		t.Fatalf("Failed to close session pool: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Running call must finish before logout: %v", err)
+	}
+
+	if _, err := pool.GetLUNs(); !errors.Is(err, manager.ErrPoolClosed) {
+		t.Fatalf("Pool closed error expected: %v", err)
+	}
+}
+
+func TestSessionPool_SharedCache(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	pool, err := manager.NewSessionPool(fake.URL, manager.StaticCredentials{Username: "admin", Password: "admin"}, &manager.ConfigOptions{
+		APICallTimeout: 5 * time.Second,
+		Cache:          &manager.CacheOptions{ISCSITargetsTTL: time.Minute},
+	}, &manager.PoolOptions{Size: 2})
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer pool.Close()
+
+	// the sessions must be released on failure, so never stop the test within Do()
+	err = pool.Do(func(reader *manager.QnapSession) error {
+		// fill the cache
+		if targets, err := reader.GetISCSITargets(); err != nil || len(targets) != 0 {
+			return fmt.Errorf("no targets expected: %v, %v", targets, err)
+		}
+
+		// mutate through the other session
+		return pool.Do(func(writer *manager.QnapSession) error {
+			if writer == reader {
+				return errors.New("different sessions expected")
+			}
+
+			_, err := writer.CreateISCSITarget("k8s", "")
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+
+	// both sessions have to see the change
+	check := func(reader *manager.QnapSession) error {
+		targets, err := reader.GetISCSITargets()
+		if err == nil && len(targets) != 1 {
+			return fmt.Errorf("stale cache of session %p: %v targets", reader, len(targets))
+		}
+		return err
+	}
+
+	err = pool.Do(func(reader *manager.QnapSession) error {
+		if err := check(reader); err != nil {
+			return err
+		}
+		return pool.Do(check)
+	})
+	if err != nil {
+		t.Fatalf("Change not visible to all sessions: %v", err)
+	}
+}
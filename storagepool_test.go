@@ -0,0 +1,65 @@
+package manager_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+	"github.com/nine-lives-later/go-qnap-disk-manager/qnapfake"
+)
+
+func TestGetStoragePoolsEx_ConcurrentPartialResults(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	for poolID := 2; poolID <= 6; poolID++ {
+		fake.AddStoragePool(poolID, 1<<40)
+	}
+
+	var meter concurrencyMeter
+
+	// the information of pool 3 is rejected
+	fake.SetInterceptor(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/cgi-bin/disk/disk_manage.cgi" || r.FormValue("store") != "poolInfo" {
+			return false
+		}
+
+		meter.track(20 * time.Millisecond)
+
+		if r.FormValue("poolID") == "3" {
+			qnapfake.WriteResult(w, "-1")
+			return true
+		}
+		return false
+	})
+
+	s := connectFake(t, fake)
+
+	poolIDs, err := s.GetStoragePoolIDs()
+	if err != nil || len(poolIDs) != 6 {
+		t.Fatalf("Unexpected pool IDs: %v, %v", poolIDs, err)
+	}
+
+	// all or nothing
+	pools, err := s.GetStoragePoolsEx(&manager.StoragePoolOptions{Concurrency: 2})
+	if err == nil || pools != nil {
+		t.Fatalf("Error without pools expected: %v, %v", pools, err)
+	}
+	// the requests are parallel, but limited by the concurrency
+	if maxActive := meter.max(); maxActive > 2 || maxActive <= 1 {
+		t.Fatalf("Expected parallel requests limited to 2, got %v concurrent requests", maxActive)
+	}
+
+	// partial results
+	pools, err = s.GetStoragePoolsEx(&manager.StoragePoolOptions{Concurrency: 2, PartialResults: true})
+
+	var poolErr *manager.StoragePoolError
+	if !errors.As(err, &poolErr) || poolErr.PoolID != 3 {
+		t.Fatalf("Error of pool 3 expected: %v", err)
+	}
+	if len(pools) != 5 || pools[1].PoolID != 2 || pools[2].PoolID != 4 {
+		t.Fatalf("Unexpected pools: %v", pools)
+	}
+}
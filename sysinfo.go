@@ -0,0 +1,246 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+type OSFlavor string
+
+const (
+	OSFlavor_QTS      OSFlavor = "QTS"
+	OSFlavor_QuTSHero OSFlavor = "QuTS hero"
+)
+
+// SystemInfo contains the general information about the QNAP system.
+type SystemInfo struct {
+	Hostname         string
+	ModelName        string
+	DisplayModelName string
+	SerialNumber     string
+	FirmwareVersion  string
+	FirmwareNumber   string
+	FirmwareBuild    string
+	OSFlavor         OSFlavor
+	Uptime           time.Duration
+	CPUUsagePercent  float64
+	TotalMemoryMB    float64
+	FreeMemoryMB     float64
+}
+
+type systemModel struct {
+	ModelName         string `xml:"modelName"`
+	InternalModelName string `xml:"internalModelName"`
+	DisplayModelName  string `xml:"displayModelName"`
+	Platform          string `xml:"platform"`
+}
+
+type systemFirmware struct {
+	Version   string `xml:"version"`
+	Number    string `xml:"number"`
+	Build     string `xml:"build"`
+	BuildTime string `xml:"buildTime"`
+}
+
+type getSystemInfoResponse struct {
+	AuthPassed int            `xml:"authPassed"`
+	Model      systemModel    `xml:"model"`
+	Firmware   systemFirmware `xml:"firmware"`
+	Hostname   string         `xml:"hostname"`
+	Func       struct {
+		OwnContent struct {
+			Root struct {
+				ServerName   string `xml:"server_name"`
+				SerialNumber string `xml:"serial_number"`
+				CPUUsage     string `xml:"cpu_usage"`
+				TotalMemory  string `xml:"total_memory"`
+				FreeMemory   string `xml:"free_memory"`
+				UptimeDay    int64  `xml:"uptime_day"`
+				UptimeHour   int64  `xml:"uptime_hour"`
+				UptimeMin    int64  `xml:"uptime_min"`
+				UptimeSec    int64  `xml:"uptime_sec"`
+			} `xml:"root"`
+		} `xml:"ownContent"`
+	} `xml:"func"`
+}
+
+// GetSystemInfo retrieves the model, firmware and resource information of the QNAP system.
+// The result is stored on the session and available through SystemInfo() afterwards.
+func (s *QnapSession) GetSystemInfo() (*SystemInfo, error) {
+	return s.GetSystemInfoContext(context.Background())
+}
+
+// GetSystemInfoContext retrieves the model, firmware and resource information of the QNAP system.
+// The result is stored on the session and available through SystemInfo() afterwards.
+func (s *QnapSession) GetSystemInfoContext(ctx context.Context) (_ *SystemInfo, err error) {
+	ctx, span := s.startSpan(ctx, "GetSystemInfo")
+	defer func() { endSpan(span, err) }()
+
+	result, err := s.requestSystemInfo(ctx, func(req *resty.Request) (*resty.Response, error) {
+		return s.execute(req, resty.MethodPost, "cgi-bin/management/manaRequest.cgi")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.storeSystemInfo(result), nil
+}
+
+// loadSystemInfoAfterLogin retrieves the full system information using the new session.
+// It never logs-in again, as the caller holds the login lock. On failure, the information
+// of the login response is kept.
+func (s *QnapSession) loadSystemInfoAfterLogin(ctx context.Context, sessionID string) {
+	result, err := s.requestSystemInfo(ctx, func(req *resty.Request) (*resty.Response, error) {
+		return s.executeWithSessionID(ctx, req, resty.MethodPost, "cgi-bin/management/manaRequest.cgi", sessionID)
+	})
+	if err != nil {
+		s.log(slog.LevelDebug, "Failed to retrieve QNAP system information after login", "host", s.host, "error", redact(err.Error()))
+		return
+	}
+
+	s.storeSystemInfo(result)
+}
+
+func (s *QnapSession) requestSystemInfo(ctx context.Context, execute func(req *resty.Request) (*resty.Response, error)) (*getSystemInfoResponse, error) {
+	var result getSystemInfoResponse
+
+	req := s.conn.NewRequest().
+		SetContext(ctx).
+		ExpectContentType("text/xml").
+		SetQueryParam("subfunc", "sysinfo").
+		SetQueryParam("hd", "no").
+		SetQueryParam("multicpu", "1").
+		SetResult(&result)
+
+	res, err := execute(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform request: %w", err)
+	}
+	if res.StatusCode() != 200 {
+		return nil, fmt.Errorf("failed to perform request: unexpected HTTP status code: %v", res.StatusCode())
+	}
+	if result.AuthPassed != 1 {
+		return nil, fmt.Errorf("failed to perform request: %w: %v", errAuthenticationInvalid, redact(string(res.Body())))
+	}
+
+	return &result, nil
+}
+
+// storeSystemInfo stores the system information on the session, completed by the login information.
+func (s *QnapSession) storeSystemInfo(result *getSystemInfoResponse) *SystemInfo {
+	root := result.Func.OwnContent.Root
+
+	info := &SystemInfo{
+		Hostname:         result.Hostname,
+		ModelName:        result.Model.ModelName,
+		DisplayModelName: result.Model.DisplayModelName,
+		SerialNumber:     root.SerialNumber,
+		FirmwareVersion:  result.Firmware.Version,
+		FirmwareNumber:   result.Firmware.Number,
+		FirmwareBuild:    result.Firmware.Build,
+		Uptime: time.Duration(root.UptimeDay)*24*time.Hour +
+			time.Duration(root.UptimeHour)*time.Hour +
+			time.Duration(root.UptimeMin)*time.Minute +
+			time.Duration(root.UptimeSec)*time.Second,
+		CPUUsagePercent: parseLooseFloat(root.CPUUsage),
+		TotalMemoryMB:   parseLooseFloat(root.TotalMemory),
+		FreeMemoryMB:    parseLooseFloat(root.FreeMemory),
+	}
+
+	// fill in the missing values from the login
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if info.Hostname == "" {
+		info.Hostname = root.ServerName
+	}
+	if info.Hostname == "" && s.systemInfo != nil {
+		info.Hostname = s.systemInfo.Hostname
+	}
+	if info.ModelName == "" && s.systemInfo != nil {
+		info.ModelName = s.systemInfo.ModelName
+		info.DisplayModelName = s.systemInfo.DisplayModelName
+	}
+	if info.FirmwareVersion == "" && s.systemInfo != nil {
+		info.FirmwareVersion = s.systemInfo.FirmwareVersion
+		info.FirmwareNumber = s.systemInfo.FirmwareNumber
+		info.FirmwareBuild = s.systemInfo.FirmwareBuild
+	}
+
+	info.OSFlavor, _, _ = parseFirmwareVersion(info.FirmwareVersion)
+
+	s.setSystemInfoLocked(info)
+
+	return info
+}
+
+// SystemInfo returns the system information known to the session, retrieved on login.
+// If the full information could not be retrieved on login, only hostname, model and firmware
+// are available; call GetSystemInfo() to retrieve the full and current information.
+func (s *QnapSession) SystemInfo() *SystemInfo {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.systemInfo
+}
+
+// Capabilities returns the API capabilities of the connected firmware.
+func (s *QnapSession) Capabilities() Capabilities {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.capabilities
+}
+
+// setSystemInfoLocked stores the system information. The caller must hold the write lock.
+func (s *QnapSession) setSystemInfoLocked(info *SystemInfo) {
+	s.systemInfo = info
+	s.capabilities = s.options.capabilities(info.FirmwareVersion)
+}
+
+// firmwareDetected checks whether the capabilities are based on a known firmware version or set explicitly.
+func (s *QnapSession) firmwareDetected() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.options != nil && s.options.Capabilities != nil {
+		return true
+	}
+	return s.systemInfo != nil && s.systemInfo.FirmwareVersion != ""
+}
+
+// parseFirmwareVersion splits a firmware version like "4.5.4" or "h5.0.1" (QuTS hero)
+// into the OS flavor and its major and minor version number.
+func parseFirmwareVersion(version string) (flavor OSFlavor, major, minor int) {
+	flavor = OSFlavor_QTS
+
+	version = strings.TrimSpace(version)
+	if strings.HasPrefix(strings.ToLower(version), "h") {
+		flavor = OSFlavor_QuTSHero
+		version = version[1:]
+	}
+
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) > 0 {
+		major, _ = strconv.Atoi(parts[0])
+	}
+	if len(parts) > 1 {
+		minor, _ = strconv.Atoi(parts[1])
+	}
+
+	return flavor, major, minor
+}
+
+// parseLooseFloat parses values like "4.1 %" or "3873.4" and returns zero on failure.
+func parseLooseFloat(value string) float64 {
+	value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "%"))
+
+	f, _ := strconv.ParseFloat(value, 64)
+	return f
+}
@@ -0,0 +1,50 @@
+package manager
+
+import (
+	"testing"
+)
+
+func TestGetSystemInfo(t *testing.T) {
+	s := createTestSession(t)
+	defer s.Logout()
+
+	info, err := s.GetSystemInfo()
+	if err != nil {
+		t.Fatalf("Failed to retrieve system information: %v", err)
+	}
+	if info.ModelName == "" {
+		t.Fatalf("Missing model name")
+	}
+	if info.FirmwareVersion == "" {
+		t.Fatalf("Missing firmware version")
+	}
+
+	t.Logf("Connected to %v (%v %v build %v)", info.ModelName, info.OSFlavor, info.FirmwareVersion, info.FirmwareBuild)
+}
+
+func TestParseFirmwareVersion(t *testing.T) {
+	flavor, major, minor := parseFirmwareVersion("4.5.4")
+	if flavor != OSFlavor_QTS || major != 4 || minor != 5 {
+		t.Fatalf("Wrong QTS version parsed: %v %v.%v", flavor, major, minor)
+	}
+
+	flavor, major, minor = parseFirmwareVersion("h5.0.1")
+	if flavor != OSFlavor_QuTSHero || major != 5 || minor != 0 {
+		t.Fatalf("Wrong QuTS hero version parsed: %v %v.%v", flavor, major, minor)
+	}
+}
+
+func TestLookupCapabilities(t *testing.T) {
+	if caps := lookupCapabilities(""); caps != defaultCapabilities {
+		t.Fatalf("Default capabilities expected for unknown firmware: %+v", caps)
+	}
+	if caps := lookupCapabilities("5.1.0"); caps.ISCSITarget != "lio" || caps.ISCSIBackend != "dm" {
+		t.Fatalf("Wrong capabilities for QTS 5: %+v", caps)
+	}
+	if caps := lookupCapabilities("4.1.2"); caps != defaultCapabilities {
+		t.Fatalf("Wrong capabilities for QTS 4.1: %+v", caps)
+	}
+	if caps := lookupCapabilities("h5.0.1"); caps.OSFlavor != OSFlavor_QuTSHero || caps.ISCSIBackend != "zfs" {
+		t.Fatalf("Wrong capabilities for QuTS hero: %+v", caps)
+	}
+}
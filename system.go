@@ -1,72 +1,265 @@
 package manager
 
 import (
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-resty/resty/v2"
 )
 
+// ErrPermissionDenied is returned by calls that require administrator privileges
+// when the logged-in user is not an administrator.
+var ErrPermissionDenied = errors.New("permission denied: administrator privileges required")
+
+// ErrAuthenticationFailed is returned by Login when the NAS rejected the credentials.
+var ErrAuthenticationFailed = errors.New("authentication failed")
+
+// ErrSecurityCodeRequired is returned by Login when the account has 2-step verification
+// enabled and no security code (e.g. TOTP) was provided.
+var ErrSecurityCodeRequired = errors.New("2-step verification security code required")
+
+// ErrSecurityCodeInvalid is returned by Login when the NAS rejected the provided
+// 2-step verification security code, e.g. an expired TOTP.
+var ErrSecurityCodeInvalid = errors.New("2-step verification security code rejected")
+
+// errAuthenticationInvalid is returned when the NAS rejected the session of a request.
+var errAuthenticationInvalid = errors.New("authentication invalid")
+
+// SecurityCodeProvider provides the 2-step verification security code (e.g. TOTP) on login.
+type SecurityCodeProvider interface {
+	SecurityCode(username string) (string, error)
+}
+
+// SecurityCodeProviderFunc is a function implementing the SecurityCodeProvider interface.
+type SecurityCodeProviderFunc func(username string) (string, error)
+
+// SecurityCode calls the function.
+func (f SecurityCodeProviderFunc) SecurityCode(username string) (string, error) {
+	return f(username)
+}
+
 type loginResponse struct {
-	Username   string `xml:"username"`
-	Hostname   string `xml:"hostname"`
-	IsAdmin    string `xml:"isAdmin"`
-	SessionID  string `xml:"authSid"`
-	AuthPassed int    `xml:"authPassed"`
+	Username   string         `xml:"username"`
+	Hostname   string         `xml:"hostname"`
+	IsAdmin    string         `xml:"isAdmin"`
+	SessionID  string         `xml:"authSid"`
+	AuthPassed int            `xml:"authPassed"`
+	Need2SV    int            `xml:"need_2sv"`
+	QToken     string         `xml:"qtoken"`
+	Model      systemModel    `xml:"model"`
+	Firmware   systemFirmware `xml:"firmware"`
 }
 
 // Login perform the authentication against the QNAP storage.
 // Any existing session will be logged-out, first.
-func (s *QnapSession) Login(username, password string) error {
+//
+// If the account has 2-step verification enabled, the security code is requested
+// from the SecurityCodeProvider of the ConfigOptions. Without provider,
+// ErrSecurityCodeRequired is returned and LoginWithOTP() has to be used.
+func (s *QnapSession) Login(username, password string) (err error) {
+	ctx, span := s.startSpan(context.Background(), "Login")
+	defer func() { endSpan(span, err) }()
+
+	s.loginLock.Lock()
+	defer s.loginLock.Unlock()
+
+	return s.loginPassword(ctx, username, password)
+}
+
+// LoginWithOTP perform the authentication against the QNAP storage using
+// the 2-step verification security code (e.g. TOTP).
+func (s *QnapSession) LoginWithOTP(username, password, securityCode string) (err error) {
+	ctx, span := s.startSpan(context.Background(), "LoginWithOTP")
+	defer func() { endSpan(span, err) }()
+
+	s.loginLock.Lock()
+	defer s.loginLock.Unlock()
+
+	return s.login(ctx, username, map[string]string{
+		"pwd":           encodePassword(password),
+		"security_code": securityCode,
+	})
+}
+
+// LoginWithToken perform the authentication against the QNAP storage using
+// the long-lived token of a previous login with RememberMe enabled (see Token()).
+func (s *QnapSession) LoginWithToken(username, token string) (err error) {
+	ctx, span := s.startSpan(context.Background(), "LoginWithToken")
+	defer func() { endSpan(span, err) }()
+
+	s.loginLock.Lock()
+	defer s.loginLock.Unlock()
+
+	return s.login(ctx, username, map[string]string{
+		"qtoken": token,
+	})
+}
+
+// Token returns the long-lived "remember me" token of the current login.
+// It is only available if RememberMe is enabled in the ConfigOptions.
+func (s *QnapSession) Token() string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.token
+}
+
+// loginPassword performs the login and asks for the 2-step verification security code, if required.
+// The provider is asked only once, a rejected code fails with ErrSecurityCodeInvalid.
+// The caller must hold the login lock.
+func (s *QnapSession) loginPassword(ctx context.Context, username, password string) error {
+	err := s.login(ctx, username, map[string]string{
+		"pwd": encodePassword(password),
+	})
+	if errors.Is(err, ErrSecurityCodeRequired) && s.options != nil && s.options.SecurityCodeProvider != nil {
+		code, err := s.options.SecurityCodeProvider.SecurityCode(username)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve security code: %w", err)
+		}
+
+		return s.login(ctx, username, map[string]string{
+			"pwd":           encodePassword(password),
+			"security_code": code,
+		})
+	}
+
+	return err
+}
+
+// login performs the login request. The caller must hold the login lock.
+func (s *QnapSession) login(ctx context.Context, username string, credentials map[string]string) error {
 	// make sure to close any existing sessions
-	s.Logout()
+	s.logout(ctx)
 
-	// perform login
+	rememberMe := s.options != nil && s.options.RememberMe
+
+	// perform login (credentials are sent within the body, never within the URL)
 	var result loginResponse
 
+	formData := map[string]string{
+		"user":  username,
+		"remme": boolToIntStr(rememberMe),
+	}
+	for k, v := range credentials {
+		formData[k] = v
+	}
+
+	start := time.Now()
+
 	res, err := s.conn.NewRequest(). // see https://download.qnap.com/dev/API_QNAP_QTS_Authentication.pdf
+						SetContext(ctx).
 						ExpectContentType("application/json").
-						SetQueryParam("user", username).
-						SetQueryParam("pwd", encodePassword(password)).
-						SetQueryParam("remme", "0").
+						SetFormData(formData).
 						SetResult(&result).
-						Get("cgi-bin/authLogin.cgi")
+						Post("cgi-bin/authLogin.cgi")
+
+	s.log(slog.LevelDebug, "QNAP login", "host", s.host, "user", username, "rememberMe", rememberMe, "duration", time.Since(start), "authPassed", result.AuthPassed, "need2SV", result.Need2SV)
+
 	if err != nil {
-		return fmt.Errorf("failed to perform request: %v", err)
+		return fmt.Errorf("failed to perform request: %w", err)
 	}
 	if res.StatusCode() != 200 {
 		return fmt.Errorf("failed to perform request: unexpected HTTP status code: %v", res.StatusCode())
 	}
+	if result.Need2SV == 1 && result.AuthPassed != 1 {
+		if _, ok := credentials["security_code"]; ok {
+			return fmt.Errorf("failed to perform request: %w", ErrSecurityCodeInvalid)
+		}
+		return fmt.Errorf("failed to perform request: %w", ErrSecurityCodeRequired)
+	}
 	if result.AuthPassed != 1 {
-		return fmt.Errorf("failed to perform request: authentication failed: %v", string(res.Body()))
+		return fmt.Errorf("failed to perform request: %w: %v", ErrAuthenticationFailed, redact(string(res.Body())))
 	}
 
+	// remember the system information provided by the login
+	flavor, _, _ := parseFirmwareVersion(result.Firmware.Version)
+
+	s.lock.Lock()
+
 	s.sessionID = result.SessionID
-	s.conn.SetQueryParam("sid", s.sessionID)
+	s.token = result.QToken
+	s.username = result.Username
+	s.nasHostname = result.Hostname
+	s.isAdmin = result.IsAdmin == "1"
+
+	s.setSystemInfoLocked(&SystemInfo{
+		Hostname:         result.Hostname,
+		ModelName:        result.Model.ModelName,
+		DisplayModelName: result.Model.DisplayModelName,
+		FirmwareVersion:  result.Firmware.Version,
+		FirmwareNumber:   result.Firmware.Number,
+		FirmwareBuild:    result.Firmware.Build,
+		OSFlavor:         flavor,
+	})
+
+	s.lock.Unlock()
+
+	// complete the system information, e.g. serial number and uptime
+	s.loadSystemInfoAfterLogin(ctx, result.SessionID)
+
+	// don't guess silently, the QTS parameters may not work for other firmware
+	if !s.firmwareDetected() {
+		s.log(slog.LevelWarn, "QNAP firmware version unknown, assuming the QTS API parameters; set ConfigOptions.Capabilities to override", "host", s.host)
+	}
 
 	return nil
 }
 
 // Logout invalidates the session.
-func (s *QnapSession) Logout() error {
+func (s *QnapSession) Logout() (err error) {
+	ctx, span := s.startSpan(context.Background(), "Logout")
+	defer func() { endSpan(span, err) }()
+
+	s.loginLock.Lock()
+	defer s.loginLock.Unlock()
+
+	return s.logout(ctx)
+}
+
+// logout performs the logout request. The caller must hold the login lock.
+func (s *QnapSession) logout(ctx context.Context) error {
+	sessionID := s.SessionID()
+
 	// no logged-in?
-	if s.sessionID == "" {
+	if sessionID == "" {
 		return nil
 	}
 
-	res, err := s.conn.NewRequest().
-		Get("cgi-bin/authLogout.cgi")
+	res, err := s.executeWithSessionID(ctx, s.conn.NewRequest(), resty.MethodPost, "cgi-bin/authLogout.cgi", sessionID)
 	if err != nil {
-		return fmt.Errorf("failed to perform request: %v", err)
+		return fmt.Errorf("failed to perform request: %w", err)
 	}
 	if res.StatusCode() != 200 {
 		return fmt.Errorf("failed to perform request: unexpected HTTP status code: %v", res.StatusCode())
 	}
 
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	s.sessionID = ""
-	s.conn.SetQueryParam("sid", "")
+	s.token = ""
+	s.username = ""
+	s.nasHostname = ""
+	s.isAdmin = false
 
 	return nil
 }
 
+// requireAdmin fails if the logged-in user lacks administrator privileges.
+func (s *QnapSession) requireAdmin(operation string) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if !s.isAdmin {
+		return fmt.Errorf("failed to %v: user '%v': %w", operation, s.username, ErrPermissionDenied)
+	}
+	return nil
+}
+
 func encodePassword(pwd string) string {
 	return base64.StdEncoding.EncodeToString([]byte(pwd))
 }
@@ -1,48 +1,59 @@
 package manager
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func createTestSession(t *testing.T) *QnapSession {
-	return createTestSessionEx(t, "", "")
+	var credentials CredentialProvider = EnvCredentials{}
+
+	if _, _, err := credentials.Credentials(); err != nil {
+		credentials = StaticCredentials{Username: "unittest-user", Password: "t3st123!!!"}
+	}
+
+	return createTestSessionEx(t, credentials)
 }
 
-func createTestSessionEx(t *testing.T, username, password string) *QnapSession {
-	// retrieve api auth if undefined
-	if username == "" {
-		username = os.Getenv("QNAP_USER")
+func createTestSessionEx(t *testing.T, credentials CredentialProvider) *QnapSession {
+	// create the session
+	session, err := ConnectWithCredentials(getTestHost(), credentials, getTestConfigOptions())
 
-		if username == "" {
-			username = "unittest-user"
-		}
+	if err != nil {
+		t.Fatalf("Failed to connect to QNAP File Station API: %v", err)
 	}
 
-	if password == "" {
-		password = os.Getenv("QNAP_PWD")
+	return session
+}
 
-		if password == "" {
-			password = "t3st123!!!"
-		}
+func getTestConfigOptions() *ConfigOptions {
+	options := &ConfigOptions{
+		APICallTimeout:         60 * time.Second,
+		CertificateFingerprint: os.Getenv("QNAP_CERT_FINGERPRINT"),
 	}
 
-	// retrieve hostname
+	// the test system uses a self-signed certificate
+	if options.CertificateFingerprint == "" {
+		options.IgnoreInvalidSSLCertificate = true
+	}
+
+	return options
+}
+
+func getTestHost() string {
 	host := os.Getenv("QNAP_HOSTNAME")
 
 	if host == "" {
 		host = "192.168.211.110:443"
 	}
 
-	// create the session
-	session, err := Connect(host, username, password, nil)
-
-	if err != nil {
-		t.Fatalf("Failed to connect to QNAP File Station API: %v", err)
-	}
-
-	return session
+	return host
 }
 
 func TestPasswordEncode(t *testing.T) {
@@ -68,6 +79,12 @@ func TestInvalidHost(t *testing.T) {
 func TestConnect(t *testing.T) {
 	s := createTestSession(t)
 
+	if s.Username() == "" {
+		t.Fatalf("Missing username")
+	}
+
+	t.Logf("Logged in as %v on %v (admin: %v)", s.Username(), s.NASHostname(), s.IsAdmin())
+
 	// real logout
 	err := s.Logout()
 	if err != nil {
@@ -82,13 +99,63 @@ func TestConnect(t *testing.T) {
 }
 
 func TestConnect_InvalidLogin(t *testing.T) {
-	host := os.Getenv("QNAP_HOSTNAME")
-	if host == "" {
-		host = "192.168.211.110:443"
+	_, err := Connect(getTestHost(), "unkn0wnUs3r", "!nval1dP@ssw0rd", getTestConfigOptions())
+	if !errors.Is(err, ErrAuthenticationFailed) {
+		t.Fatalf("Authentication error expected: %v", err)
 	}
+}
 
-	_, err := Connect(host, "unkn0wnUs3r", "!nval1dP@ssw0rd", nil)
-	if err == nil {
-		t.Fatal("Error expected")
+func TestLogin_SecurityCode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+
+		if r.URL.Query().Get("pwd") != "" || r.URL.Query().Get("sid") != "" {
+			t.Errorf("Credentials must not be sent within the URL: %v", r.URL)
+		}
+
+		switch {
+		case r.URL.Path == "/cgi-bin/authLogout.cgi":
+			fmt.Fprint(w, "<QDocRoot></QDocRoot>")
+		case r.FormValue("security_code") == "123456":
+			fmt.Fprint(w, "<QDocRoot><authPassed><![CDATA[1]]></authPassed><authSid><![CDATA[abcdef]]></authSid><username><![CDATA[admin]]></username><isAdmin><![CDATA[1]]></isAdmin></QDocRoot>")
+		default:
+			fmt.Fprint(w, "<QDocRoot><authPassed><![CDATA[0]]></authPassed><need_2sv><![CDATA[1]]></need_2sv></QDocRoot>")
+		}
+	}))
+	defer server.Close()
+
+	_, err := Connect(server.URL, "admin", "admin", &ConfigOptions{APICallTimeout: 5 * time.Second})
+	if !errors.Is(err, ErrSecurityCodeRequired) {
+		t.Fatalf("Security code error expected: %v", err)
+	}
+
+	s, err := Connect(server.URL, "admin", "admin", &ConfigOptions{
+		APICallTimeout: 5 * time.Second,
+		SecurityCodeProvider: SecurityCodeProviderFunc(func(username string) (string, error) {
+			return "123456", nil
+		}),
+	})
+	if err != nil {
+		t.Fatalf("Failed to login with security code: %v", err)
+	}
+	if !s.IsAdmin() {
+		t.Fatalf("Admin privileges expected")
+	}
+
+	// a rejected code is not asked for again
+	prompts := 0
+
+	_, err = Connect(server.URL, "admin", "admin", &ConfigOptions{
+		APICallTimeout: 5 * time.Second,
+		SecurityCodeProvider: SecurityCodeProviderFunc(func(username string) (string, error) {
+			prompts++
+			return "000000", nil
+		}),
+	})
+	if !errors.Is(err, ErrSecurityCodeInvalid) || errors.Is(err, ErrSecurityCodeRequired) {
+		t.Fatalf("Invalid security code error expected: %v", err)
+	}
+	if prompts != 1 {
+		t.Fatalf("Expected the provider to be asked once, got %v prompts", prompts)
 	}
 }
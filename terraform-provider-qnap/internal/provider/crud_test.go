@@ -0,0 +1,197 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+	"github.com/nine-lives-later/go-qnap-disk-manager/qnapfake"
+)
+
+// The CRUD tests run the resources against the fake NAS without the Terraform CLI,
+// which is required by the acceptance tests.
+
+func newTestClient(t *testing.T) (manager.Client, *qnapfake.Server) {
+	fake := qnapfake.NewServer("admin", "admin")
+	t.Cleanup(fake.Close)
+
+	s, err := manager.Connect(fake.URL, "admin", "admin", &manager.ConfigOptions{APICallTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s, fake
+}
+
+func resourceSchema(t *testing.T, r resource.Resource) resource.SchemaResponse {
+	var resp resource.SchemaResponse
+	r.Schema(context.Background(), resource.SchemaRequest{}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Invalid schema: %v", resp.Diagnostics)
+	}
+	return resp
+}
+
+// newPlan builds a plan with the values, all other attributes are unknown.
+func newPlan(t *testing.T, r resource.Resource, values map[string]tftypes.Value) tfsdk.Plan {
+	schema := resourceSchema(t, r).Schema
+	objectType := schema.Type().TerraformType(context.Background()).(tftypes.Object)
+
+	attrs := map[string]tftypes.Value{}
+	for name, typ := range objectType.AttributeTypes {
+		if v, ok := values[name]; ok {
+			attrs[name] = v
+		} else {
+			attrs[name] = tftypes.NewValue(typ, tftypes.UnknownValue)
+		}
+	}
+
+	return tfsdk.Plan{Schema: schema, Raw: tftypes.NewValue(objectType, attrs)}
+}
+
+func emptyState(t *testing.T, r resource.Resource) tfsdk.State {
+	schema := resourceSchema(t, r).Schema
+	return tfsdk.State{Schema: schema, Raw: tftypes.NewValue(schema.Type().TerraformType(context.Background()), nil)}
+}
+
+func TestLUNResource_CRUD(t *testing.T) {
+	ctx := context.Background()
+	client, fake := newTestClient(t)
+
+	r := &lunResource{client: client}
+
+	create := resource.CreateResponse{State: emptyState(t, r)}
+	r.Create(ctx, resource.CreateRequest{Plan: newPlan(t, r, map[string]tftypes.Value{
+		"name":      tftypes.NewValue(tftypes.String, "data01"),
+		"pool_id":   tftypes.NewValue(tftypes.Number, 1),
+		"size_gb":   tftypes.NewValue(tftypes.Number, 10),
+		"thin":      tftypes.NewValue(tftypes.Bool, false),
+		"ssd_cache": tftypes.NewValue(tftypes.Bool, false),
+		"threshold": tftypes.NewValue(tftypes.Number, 90),
+	})}, &create)
+	if create.Diagnostics.HasError() {
+		t.Fatalf("Failed to create LUN: %v", create.Diagnostics)
+	}
+
+	var state lunResourceModel
+	create.State.Get(ctx, &state)
+	if state.Name.ValueString() != "data01" || state.Thin.ValueBool() || state.Threshold.ValueInt64() != 90 || state.NAA.ValueString() == "" {
+		t.Fatalf("Wrong state: %+v", state)
+	}
+
+	// import by index
+	imported := resource.ImportStateResponse{State: emptyState(t, r)}
+	r.ImportState(ctx, resource.ImportStateRequest{ID: state.ID.ValueString()}, &imported)
+	read := resource.ReadResponse{State: imported.State}
+	r.Read(ctx, resource.ReadRequest{State: imported.State}, &read)
+	if read.Diagnostics.HasError() {
+		t.Fatalf("Failed to read imported LUN: %v", read.Diagnostics)
+	}
+
+	var importedState lunResourceModel
+	read.State.Get(ctx, &importedState)
+	if importedState != state {
+		t.Fatalf("Imported state differs:\n%+v\n%+v", importedState, state)
+	}
+
+	del := resource.DeleteResponse{State: create.State}
+	r.Delete(ctx, resource.DeleteRequest{State: create.State}, &del)
+	if del.Diagnostics.HasError() {
+		t.Fatalf("Failed to delete LUN: %v", del.Diagnostics)
+	}
+	if len(fake.LUNs()) != 0 {
+		t.Fatalf("LUN not deleted")
+	}
+
+	// read removes the deleted LUN from the state
+	read = resource.ReadResponse{State: create.State}
+	r.Read(ctx, resource.ReadRequest{State: create.State}, &read)
+	if !read.State.Raw.IsNull() {
+		t.Fatalf("Deleted LUN not removed from state")
+	}
+}
+
+func TestMappingResource_CRUD(t *testing.T) {
+	ctx := context.Background()
+	client, fake := newTestClient(t)
+
+	target, err := client.CreateISCSITarget("k8s", "")
+	if err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+	lun, err := client.CreateBlockBasedLUN(1, "data01", 10, manager.LUNAllocateMode_Thin, false, 80)
+	if err != nil {
+		t.Fatalf("Failed to create LUN: %v", err)
+	}
+
+	r := &mappingResource{client: client}
+
+	create := resource.CreateResponse{State: emptyState(t, r)}
+	r.Create(ctx, resource.CreateRequest{Plan: newPlan(t, r, map[string]tftypes.Value{
+		"lun_index":    tftypes.NewValue(tftypes.Number, lun.LUNIndex),
+		"target_index": tftypes.NewValue(tftypes.Number, target.TargetIndex),
+	})}, &create)
+	if create.Diagnostics.HasError() {
+		t.Fatalf("Failed to create mapping: %v", create.Diagnostics)
+	}
+	if fake.LUNs()[0].LUNTargetList.SingleRow == nil {
+		t.Fatalf("LUN not assigned")
+	}
+
+	del := resource.DeleteResponse{State: create.State}
+	r.Delete(ctx, resource.DeleteRequest{State: create.State}, &del)
+	if del.Diagnostics.HasError() {
+		t.Fatalf("Failed to delete mapping: %v", del.Diagnostics)
+	}
+	if fake.LUNs()[0].LUNTargetList.SingleRow != nil {
+		t.Fatalf("LUN not unassigned")
+	}
+}
+
+func TestMappingResource_WrongTarget(t *testing.T) {
+	ctx := context.Background()
+	client, fake := newTestClient(t)
+
+	var targets []*manager.ISCSITarget
+	for _, name := range []string{"k8s", "backup"} {
+		target, err := client.CreateISCSITarget(name, "")
+		if err != nil {
+			t.Fatalf("Failed to create target: %v", err)
+		}
+		targets = append(targets, target)
+	}
+	lun, err := client.CreateBlockBasedLUN(1, "data01", 10, manager.LUNAllocateMode_Thin, false, 80)
+	if err != nil {
+		t.Fatalf("Failed to create LUN: %v", err)
+	}
+	if err := client.AssignLUN(lun.LUNIndex, targets[0].TargetIndex); err != nil {
+		t.Fatalf("Failed to assign LUN: %v", err)
+	}
+
+	// the NAS accepts the assignment, but the LUN stays assigned to the other target
+	fake.SetInterceptor(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/cgi-bin/disk/iscsi_target_setting.cgi" && r.FormValue("func") == "add_lun" {
+			qnapfake.WriteResult(w, "0")
+			return true
+		}
+		return false
+	})
+
+	r := &mappingResource{client: client}
+
+	create := resource.CreateResponse{State: emptyState(t, r)}
+	r.Create(ctx, resource.CreateRequest{Plan: newPlan(t, r, map[string]tftypes.Value{
+		"lun_index":    tftypes.NewValue(tftypes.Number, lun.LUNIndex),
+		"target_index": tftypes.NewValue(tftypes.Number, targets[1].TargetIndex),
+	})}, &create)
+	if !create.Diagnostics.HasError() {
+		t.Fatal("Error expected for the LUN assigned to the wrong target")
+	}
+}
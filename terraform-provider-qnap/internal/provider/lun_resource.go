@@ -0,0 +1,219 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+)
+
+var (
+	_ resource.Resource                = (*lunResource)(nil)
+	_ resource.ResourceWithConfigure   = (*lunResource)(nil)
+	_ resource.ResourceWithImportState = (*lunResource)(nil)
+)
+
+// lunResource manages a block-based LUN. The QNAP API does not support changing
+// an existing LUN, so every change replaces it.
+type lunResource struct {
+	client manager.Client
+}
+
+type lunResourceModel struct {
+	ID            types.String `tfsdk:"id"`
+	Index         types.Int64  `tfsdk:"index"`
+	Name          types.String `tfsdk:"name"`
+	PoolID        types.Int64  `tfsdk:"pool_id"`
+	SizeGB        types.Int64  `tfsdk:"size_gb"`
+	Thin          types.Bool   `tfsdk:"thin"`
+	SSDCache      types.Bool   `tfsdk:"ssd_cache"`
+	Threshold     types.Int64  `tfsdk:"threshold"`
+	NAA           types.String `tfsdk:"naa"`
+	CapacityBytes types.Int64  `tfsdk:"capacity_bytes"`
+}
+
+func newLUNResource() resource.Resource {
+	return &lunResource{}
+}
+
+func (r *lunResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_iscsi_lun"
+}
+
+func (r *lunResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Block-based iSCSI LUN within a storage pool. Changes replace the LUN, as existing LUNs cannot be modified.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description:   "LUN index as string.",
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"index": schema.Int64Attribute{
+				Description:   "LUN index assigned by the QNAP system.",
+				Computed:      true,
+				PlanModifiers: []planmodifier.Int64{int64planmodifier.UseStateForUnknown()},
+			},
+			"name": schema.StringAttribute{
+				Description:   "Name of the LUN.",
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"pool_id": schema.Int64Attribute{
+				Description:   "Storage pool to create the LUN in.",
+				Required:      true,
+				PlanModifiers: []planmodifier.Int64{int64planmodifier.RequiresReplace()},
+			},
+			"size_gb": schema.Int64Attribute{
+				Description:   "Capacity of the LUN in GB.",
+				Required:      true,
+				PlanModifiers: []planmodifier.Int64{int64planmodifier.RequiresReplace()},
+			},
+			"thin": schema.BoolAttribute{
+				Description:   "Allocate the capacity on demand. Defaults to true.",
+				Optional:      true,
+				Computed:      true,
+				Default:       booldefault.StaticBool(true),
+				PlanModifiers: []planmodifier.Bool{boolplanmodifier.RequiresReplace()},
+			},
+			"ssd_cache": schema.BoolAttribute{
+				Description:   "Use the SSD cache. Defaults to false.",
+				Optional:      true,
+				Computed:      true,
+				Default:       booldefault.StaticBool(false),
+				PlanModifiers: []planmodifier.Bool{boolplanmodifier.RequiresReplace()},
+			},
+			"threshold": schema.Int64Attribute{
+				Description:   "Alert threshold in percent. Defaults to 80.",
+				Optional:      true,
+				Computed:      true,
+				Default:       int64default.StaticInt64(80),
+				PlanModifiers: []planmodifier.Int64{int64planmodifier.RequiresReplace()},
+			},
+			"naa": schema.StringAttribute{
+				Description:   "NAA identifier of the LUN, as seen by the initiators.",
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"capacity_bytes": schema.Int64Attribute{
+				Description:   "Capacity of the LUN in bytes.",
+				Computed:      true,
+				PlanModifiers: []planmodifier.Int64{int64planmodifier.UseStateForUnknown()},
+			},
+		},
+	}
+}
+
+func (r *lunResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = clientFromProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+func (r *lunResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan lunResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	allocateMode := manager.LUNAllocateMode_Thick
+	if plan.Thin.ValueBool() {
+		allocateMode = manager.LUNAllocateMode_Thin
+	}
+
+	lun, err := r.client.CreateBlockBasedLUNContext(ctx, int(plan.PoolID.ValueInt64()), plan.Name.ValueString(), int(plan.SizeGB.ValueInt64()),
+		allocateMode, plan.SSDCache.ValueBool(), int(plan.Threshold.ValueInt64()), nil)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to create LUN", err.Error())
+		return
+	}
+
+	plan.fromLUN(lun)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *lunResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state lunResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	lun, err := r.client.GetLUNByIndexContext(ctx, int(state.Index.ValueInt64()))
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read LUN", err.Error())
+		return
+	}
+	if lun == nil {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	state.fromLUN(lun)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *lunResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	// all configurable attributes require a replacement
+	var plan lunResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *lunResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state lunResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.client.DeleteLUNContext(ctx, int(state.Index.ValueInt64())); err != nil {
+		resp.Diagnostics.AddError("Failed to delete LUN", err.Error())
+	}
+}
+
+// ImportState imports a LUN by its index.
+func (r *lunResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	index, err := strconv.Atoi(req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid import ID", fmt.Sprintf("Expected the LUN index, got %q.", req.ID))
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("index"), int64(index))...)
+}
+
+func (m *lunResourceModel) fromLUN(lun *manager.LUN) {
+	m.ID = types.StringValue(strconv.Itoa(lun.LUNIndex))
+	m.Index = types.Int64Value(int64(lun.LUNIndex))
+	m.Name = types.StringValue(lun.LUNName)
+	m.PoolID = types.Int64Value(int64(lun.PoolID))
+	m.SizeGB = types.Int64Value(lun.CapacityBytes >> 30)
+	m.Thin = types.BoolValue(lun.LUNThinAllocate)
+	m.Threshold = types.Int64Value(int64(lun.LUNThresholdPercent))
+	m.NAA = types.StringValue(lun.LUNNAA)
+	m.CapacityBytes = types.Int64Value(lun.CapacityBytes)
+
+	// the SSD cache setting is reported inconsistently, only fill it in on import
+	if m.SSDCache.IsNull() || m.SSDCache.IsUnknown() {
+		m.SSDCache = types.BoolValue(lun.SsdCache == "yes" || lun.SsdCache == "1")
+	}
+}
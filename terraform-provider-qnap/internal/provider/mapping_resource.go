@@ -0,0 +1,178 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+)
+
+var (
+	_ resource.Resource                = (*mappingResource)(nil)
+	_ resource.ResourceWithConfigure   = (*mappingResource)(nil)
+	_ resource.ResourceWithImportState = (*mappingResource)(nil)
+)
+
+// mappingResource assigns a LUN to an iSCSI target. A LUN can only be assigned to a single target.
+type mappingResource struct {
+	client manager.Client
+}
+
+type mappingResourceModel struct {
+	ID          types.String `tfsdk:"id"`
+	LUNIndex    types.Int64  `tfsdk:"lun_index"`
+	TargetIndex types.Int64  `tfsdk:"target_index"`
+	LUNNumber   types.Int64  `tfsdk:"lun_number"`
+}
+
+func newMappingResource() resource.Resource {
+	return &mappingResource{}
+}
+
+func (r *mappingResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_lun_mapping"
+}
+
+func (r *mappingResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Assignment of a LUN to an iSCSI target.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description:   "LUN index as string.",
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"lun_index": schema.Int64Attribute{
+				Description:   "Index of the LUN to assign.",
+				Required:      true,
+				PlanModifiers: []planmodifier.Int64{int64planmodifier.RequiresReplace()},
+			},
+			"target_index": schema.Int64Attribute{
+				Description:   "Index of the iSCSI target to assign the LUN to.",
+				Required:      true,
+				PlanModifiers: []planmodifier.Int64{int64planmodifier.RequiresReplace()},
+			},
+			"lun_number": schema.Int64Attribute{
+				Description:   "Number of the LUN within the target, as seen by the initiators.",
+				Computed:      true,
+				PlanModifiers: []planmodifier.Int64{int64planmodifier.UseStateForUnknown()},
+			},
+		},
+	}
+}
+
+func (r *mappingResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = clientFromProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+func (r *mappingResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan mappingResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	lunIndex := int(plan.LUNIndex.ValueInt64())
+	targetIndex := int(plan.TargetIndex.ValueInt64())
+
+	// the volume has to be ready before assigning the LUN, e.g. if created within the same apply
+	if _, err := r.client.WaitForLUNVolumeContext(ctx, lunIndex); err != nil {
+		resp.Diagnostics.AddError("Failed to wait for LUN volume", err.Error())
+		return
+	}
+
+	if err := r.client.AssignLUNContext(ctx, lunIndex, targetIndex); err != nil {
+		resp.Diagnostics.AddError("Failed to assign LUN", err.Error())
+		return
+	}
+
+	lun, err := r.client.GetLUNByIndexContext(ctx, lunIndex)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read LUN", err.Error())
+		return
+	}
+	if !plan.fromLUN(lun) || lun.LUNTargetList.SingleRow.TargetIndex != targetIndex {
+		resp.Diagnostics.AddError("Failed to assign LUN", fmt.Sprintf("LUN %v is not assigned to target %v after the assignment.", lunIndex, targetIndex))
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *mappingResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state mappingResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	lun, err := r.client.GetLUNByIndexContext(ctx, int(state.LUNIndex.ValueInt64()))
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read LUN", err.Error())
+		return
+	}
+	if !state.fromLUN(lun) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *mappingResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	// all configurable attributes require a replacement
+	var plan mappingResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *mappingResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state mappingResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.client.UnassignLUNContext(ctx, int(state.LUNIndex.ValueInt64()), int(state.TargetIndex.ValueInt64())); err != nil {
+		resp.Diagnostics.AddError("Failed to unassign LUN", err.Error())
+	}
+}
+
+// ImportState imports the mapping of a LUN by the LUN index.
+func (r *mappingResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	index, err := strconv.Atoi(req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid import ID", fmt.Sprintf("Expected the LUN index, got %q.", req.ID))
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("lun_index"), int64(index))...)
+}
+
+// fromLUN fills in the mapping of the LUN, false if the LUN does not exist or is not assigned.
+func (m *mappingResourceModel) fromLUN(lun *manager.LUN) bool {
+	if lun == nil || lun.LUNTargetList.SingleRow == nil {
+		return false
+	}
+
+	m.ID = types.StringValue(strconv.Itoa(lun.LUNIndex))
+	m.LUNIndex = types.Int64Value(int64(lun.LUNIndex))
+	m.TargetIndex = types.Int64Value(int64(lun.LUNTargetList.SingleRow.TargetIndex))
+	m.LUNNumber = types.Int64Value(int64(lun.LUNTargetList.SingleRow.LUNNumber))
+
+	return true
+}
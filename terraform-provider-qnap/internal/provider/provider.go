@@ -0,0 +1,179 @@
+// Package provider implements the Terraform provider for QNAP systems.
+package provider
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+)
+
+var _ provider.Provider = (*qnapProvider)(nil)
+
+type qnapProvider struct {
+	version string
+}
+
+type qnapProviderModel struct {
+	Host            types.String `tfsdk:"host"`
+	Username        types.String `tfsdk:"username"`
+	Password        types.String `tfsdk:"password"`
+	Profile         types.String `tfsdk:"profile"`
+	Insecure        types.Bool   `tfsdk:"insecure"`
+	CertFingerprint types.String `tfsdk:"cert_fingerprint"`
+	Timeout         types.String `tfsdk:"timeout"`
+}
+
+// New returns the factory of the provider.
+func New(version string) func() provider.Provider {
+	return func() provider.Provider {
+		return &qnapProvider{version: version}
+	}
+}
+
+func (p *qnapProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
+	resp.TypeName = "qnap"
+	resp.Version = p.version
+}
+
+func (p *qnapProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Manages the LUNs and iSCSI targets of a QNAP system.",
+		Attributes: map[string]schema.Attribute{
+			"host": schema.StringAttribute{
+				Description: "QNAP system to connect to, e.g. storage:443. Defaults to the QNAP_HOSTNAME environment variable.",
+				Optional:    true,
+			},
+			"username": schema.StringAttribute{
+				Description: "Username to login with. Defaults to the QNAP_USER environment variable.",
+				Optional:    true,
+			},
+			"password": schema.StringAttribute{
+				Description: "Password to login with. Defaults to the QNAP_PWD environment variable.",
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"profile": schema.StringAttribute{
+				Description: "Profile of the profiles file to connect with, instead of host and credentials.",
+				Optional:    true,
+			},
+			"insecure": schema.BoolAttribute{
+				Description: "Skip the verification of the NAS certificate.",
+				Optional:    true,
+			},
+			"cert_fingerprint": schema.StringAttribute{
+				Description: "SHA-256 fingerprint of the NAS certificate to pin, e.g. a self-signed one.",
+				Optional:    true,
+			},
+			"timeout": schema.StringAttribute{
+				Description: "Timeout of every API call, e.g. 90s. Defaults to 60s.",
+				Optional:    true,
+			},
+		},
+	}
+}
+
+func (p *qnapProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var config qnapProviderModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if profile := config.Profile.ValueString(); profile != "" {
+		session, err := manager.LoadProfile(profile)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("profile"), "Failed to connect to QNAP system", err.Error())
+			return
+		}
+
+		resp.DataSourceData = session
+		resp.ResourceData = session
+		return
+	}
+
+	host := valueOrEnv(config.Host, "QNAP_HOSTNAME")
+	username := valueOrEnv(config.Username, "QNAP_USER")
+	password := valueOrEnv(config.Password, "QNAP_PWD")
+
+	if host == "" {
+		resp.Diagnostics.AddAttributeError(path.Root("host"), "Missing QNAP host", "Set the host attribute or the QNAP_HOSTNAME environment variable.")
+	}
+	if username == "" || password == "" {
+		resp.Diagnostics.AddError("Missing QNAP credentials", "Set the username and password attributes or the QNAP_USER and QNAP_PWD environment variables.")
+	}
+
+	options := &manager.ConfigOptions{
+		APICallTimeout:              60 * time.Second,
+		IgnoreInvalidSSLCertificate: config.Insecure.ValueBool(),
+		CertificateFingerprint:      config.CertFingerprint.ValueString(),
+	}
+
+	if timeout := config.Timeout.ValueString(); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("timeout"), "Invalid timeout", err.Error())
+		}
+		options.APICallTimeout = d
+	}
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	session, err := manager.Connect(host, username, password, options)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to connect to QNAP system", err.Error())
+		return
+	}
+
+	resp.DataSourceData = session
+	resp.ResourceData = session
+}
+
+func (p *qnapProvider) Resources(ctx context.Context) []func() resource.Resource {
+	return []func() resource.Resource{
+		newLUNResource,
+		newTargetResource,
+		newMappingResource,
+	}
+}
+
+func (p *qnapProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
+	return []func() datasource.DataSource{
+		newStoragePoolsDataSource,
+	}
+}
+
+func valueOrEnv(value types.String, env string) string {
+	if !value.IsNull() && !value.IsUnknown() {
+		return value.ValueString()
+	}
+	return os.Getenv(env)
+}
+
+// clientFromProviderData returns the client configured by the provider,
+// nil if the provider has not been configured yet.
+func clientFromProviderData(data any, diags *diag.Diagnostics) manager.Client {
+	if data == nil {
+		return nil
+	}
+
+	client, ok := data.(manager.Client)
+	if !ok {
+		diags.AddError("Unexpected provider data", "The provider data is not a QNAP client, please report this issue to the provider developers.")
+		return nil
+	}
+
+	return client
+}
@@ -0,0 +1,52 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+
+	"github.com/nine-lives-later/go-qnap-disk-manager/qnapfake"
+)
+
+// testAccProtoV6ProviderFactories runs the provider in-process for the acceptance tests.
+var testAccProtoV6ProviderFactories = map[string]func() (tfprotov6.ProviderServer, error){
+	"qnap": providerserver.NewProtocol6WithError(New("test")()),
+}
+
+// testAccProviderConfig returns the provider configuration connecting to the fake NAS.
+func testAccProviderConfig(fake *qnapfake.Server) string {
+	return fmt.Sprintf(`
+provider "qnap" {
+  host     = %q
+  username = "admin"
+  password = "admin"
+}
+`, fake.URL)
+}
+
+func TestProvider_Schema(t *testing.T) {
+	server, err := providerserver.NewProtocol6WithError(New("test")())()
+	if err != nil {
+		t.Fatalf("Failed to create provider server: %v", err)
+	}
+
+	resp, err := server.GetProviderSchema(context.Background(), &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatalf("Failed to get provider schema: %v", err)
+	}
+	for _, d := range resp.Diagnostics {
+		t.Errorf("Schema diagnostic: %v: %v", d.Summary, d.Detail)
+	}
+
+	for _, name := range []string{"qnap_iscsi_lun", "qnap_iscsi_target", "qnap_lun_mapping"} {
+		if resp.ResourceSchemas[name] == nil {
+			t.Errorf("Missing resource %v", name)
+		}
+	}
+	if resp.DataSourceSchemas["qnap_storage_pools"] == nil {
+		t.Errorf("Missing data source qnap_storage_pools")
+	}
+}
@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+
+	"github.com/nine-lives-later/go-qnap-disk-manager/qnapfake"
+)
+
+func TestAccStoragePoolsDataSource(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccProviderConfig(fake) + `data "qnap_storage_pools" "all" {}`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.qnap_storage_pools.all", "pools.#", "1"),
+					resource.TestCheckResourceAttr("data.qnap_storage_pools.all", "pools.0.pool_id", "1"),
+					resource.TestCheckResourceAttr("data.qnap_storage_pools.all", "pools.0.capacity_bytes", fmt.Sprint(int64(1)<<40)),
+				),
+			},
+		},
+	})
+}
+
+func TestAccLUNMapping(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+
+	config := testAccProviderConfig(fake) + `
+resource "qnap_iscsi_target" "k8s" {
+  name = "k8s"
+}
+
+resource "qnap_iscsi_lun" "data" {
+  name    = "data01"
+  pool_id = 1
+  size_gb = 10
+}
+
+resource "qnap_lun_mapping" "data" {
+  lun_index    = qnap_iscsi_lun.data.index
+  target_index = qnap_iscsi_target.k8s.index
+}
+`
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: func(*terraform.State) error {
+			if luns, targets := fake.LUNs(), fake.ISCSITargets(); len(luns) != 0 || len(targets) != 0 {
+				return fmt.Errorf("fake NAS not empty: %v LUNs, %v targets", len(luns), len(targets))
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("qnap_iscsi_target.k8s", "alias", "k8s"),
+					resource.TestCheckResourceAttrSet("qnap_iscsi_target.k8s", "iqn"),
+					resource.TestCheckResourceAttr("qnap_iscsi_lun.data", "thin", "true"),
+					resource.TestCheckResourceAttr("qnap_iscsi_lun.data", "threshold", "80"),
+					resource.TestCheckResourceAttr("qnap_iscsi_lun.data", "capacity_bytes", fmt.Sprint(int64(10)<<30)),
+					resource.TestCheckResourceAttrSet("qnap_iscsi_lun.data", "naa"),
+					resource.TestCheckResourceAttrPair("qnap_lun_mapping.data", "target_index", "qnap_iscsi_target.k8s", "index"),
+					resource.TestCheckResourceAttr("qnap_lun_mapping.data", "lun_number", "0"),
+				),
+			},
+			{
+				ResourceName:      "qnap_iscsi_lun.data",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			{
+				ResourceName:      "qnap_iscsi_target.k8s",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			{
+				ResourceName:      "qnap_lun_mapping.data",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
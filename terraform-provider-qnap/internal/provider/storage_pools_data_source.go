@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+)
+
+var (
+	_ datasource.DataSource              = (*storagePoolsDataSource)(nil)
+	_ datasource.DataSourceWithConfigure = (*storagePoolsDataSource)(nil)
+)
+
+// storagePoolsDataSource lists the storage pools and their capacity.
+type storagePoolsDataSource struct {
+	client manager.Client
+}
+
+type storagePoolsDataSourceModel struct {
+	ID    types.String       `tfsdk:"id"`
+	Pools []storagePoolModel `tfsdk:"pools"`
+}
+
+type storagePoolModel struct {
+	PoolID         types.Int64 `tfsdk:"pool_id"`
+	Status         types.Int64 `tfsdk:"status"`
+	CapacityBytes  types.Int64 `tfsdk:"capacity_bytes"`
+	AllocatedBytes types.Int64 `tfsdk:"allocated_bytes"`
+	FreesizeBytes  types.Int64 `tfsdk:"freesize_bytes"`
+}
+
+func newStoragePoolsDataSource() datasource.DataSource {
+	return &storagePoolsDataSource{}
+}
+
+func (d *storagePoolsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_storage_pools"
+}
+
+func (d *storagePoolsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Storage pools of the QNAP system.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description: "Hostname of the QNAP system.",
+				Computed:    true,
+			},
+			"pools": schema.ListNestedAttribute{
+				Description: "Storage pools, ordered as reported by the QNAP system.",
+				Computed:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"pool_id":         schema.Int64Attribute{Computed: true, Description: "ID of the storage pool."},
+						"status":          schema.Int64Attribute{Computed: true, Description: "Status code of the storage pool."},
+						"capacity_bytes":  schema.Int64Attribute{Computed: true, Description: "Capacity of the storage pool."},
+						"allocated_bytes": schema.Int64Attribute{Computed: true, Description: "Allocated space of the storage pool."},
+						"freesize_bytes":  schema.Int64Attribute{Computed: true, Description: "Free space of the storage pool."},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *storagePoolsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.client = clientFromProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+func (d *storagePoolsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	pools, err := d.client.GetStoragePoolsContext(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read storage pools", err.Error())
+		return
+	}
+
+	state := storagePoolsDataSourceModel{
+		ID:    types.StringValue(hostOf(d.client)),
+		Pools: make([]storagePoolModel, 0, len(pools)),
+	}
+
+	for _, pool := range pools {
+		state.Pools = append(state.Pools, storagePoolModel{
+			PoolID:         types.Int64Value(int64(pool.PoolID)),
+			Status:         types.Int64Value(int64(pool.PoolStatus)),
+			CapacityBytes:  types.Int64Value(pool.CapacityBytes),
+			AllocatedBytes: types.Int64Value(pool.AllocatedBytes),
+			FreesizeBytes:  types.Int64Value(pool.FreesizeBytes),
+		})
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+// hostOf returns the hostname of the client, e.g. of a *manager.QnapSession.
+func hostOf(client manager.Client) string {
+	if s, ok := client.(interface{ String() string }); ok {
+		return s.String()
+	}
+	return "qnap"
+}
@@ -0,0 +1,167 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+)
+
+var (
+	_ resource.Resource                = (*targetResource)(nil)
+	_ resource.ResourceWithConfigure   = (*targetResource)(nil)
+	_ resource.ResourceWithImportState = (*targetResource)(nil)
+)
+
+// targetResource manages an iSCSI target without authentication.
+type targetResource struct {
+	client manager.Client
+}
+
+type targetResourceModel struct {
+	ID    types.String `tfsdk:"id"`
+	Index types.Int64  `tfsdk:"index"`
+	Name  types.String `tfsdk:"name"`
+	Alias types.String `tfsdk:"alias"`
+	IQN   types.String `tfsdk:"iqn"`
+}
+
+func newTargetResource() resource.Resource {
+	return &targetResource{}
+}
+
+func (r *targetResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_iscsi_target"
+}
+
+func (r *targetResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "iSCSI target without authentication. Changes replace the target.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description:   "Target index as string.",
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"index": schema.Int64Attribute{
+				Description:   "Target index assigned by the QNAP system.",
+				Computed:      true,
+				PlanModifiers: []planmodifier.Int64{int64planmodifier.UseStateForUnknown()},
+			},
+			"name": schema.StringAttribute{
+				Description:   "Name of the target, part of the IQN.",
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"alias": schema.StringAttribute{
+				Description:   "Alias of the target. Defaults to the name.",
+				Optional:      true,
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace(), stringplanmodifier.UseStateForUnknown()},
+			},
+			"iqn": schema.StringAttribute{
+				Description:   "IQN of the target, generated by the QNAP system.",
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+		},
+	}
+}
+
+func (r *targetResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = clientFromProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+func (r *targetResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan targetResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	target, err := r.client.CreateISCSITargetContext(ctx, plan.Name.ValueString(), plan.Alias.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to create iSCSI target", err.Error())
+		return
+	}
+
+	plan.fromTarget(target)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *targetResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state targetResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	targets, err := r.client.GetISCSITargetsContext(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read iSCSI targets", err.Error())
+		return
+	}
+
+	for _, target := range targets {
+		if int64(target.TargetIndex) == state.Index.ValueInt64() {
+			state.fromTarget(target)
+			resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+			return
+		}
+	}
+
+	resp.State.RemoveResource(ctx)
+}
+
+func (r *targetResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	// all configurable attributes require a replacement
+	var plan targetResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *targetResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state targetResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.client.DeleteISCSITargetContext(ctx, int(state.Index.ValueInt64())); err != nil {
+		resp.Diagnostics.AddError("Failed to delete iSCSI target", err.Error())
+	}
+}
+
+// ImportState imports an iSCSI target by its index.
+func (r *targetResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	index, err := strconv.Atoi(req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid import ID", fmt.Sprintf("Expected the target index, got %q.", req.ID))
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("index"), int64(index))...)
+}
+
+func (m *targetResourceModel) fromTarget(target *manager.ISCSITarget) {
+	m.ID = types.StringValue(strconv.Itoa(target.TargetIndex))
+	m.Index = types.Int64Value(int64(target.TargetIndex))
+	m.Name = types.StringValue(target.TargetName)
+	m.Alias = types.StringValue(target.TargetAlias)
+	m.IQN = types.StringValue(target.TargetIQN)
+}
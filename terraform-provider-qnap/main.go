@@ -0,0 +1,29 @@
+// Command terraform-provider-qnap is a Terraform provider managing the LUNs and iSCSI targets of QNAP systems.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+
+	"github.com/nine-lives-later/go-qnap-disk-manager/terraform-provider-qnap/internal/provider"
+)
+
+var version = "dev" // set by the release build
+
+func main() {
+	var debug bool
+
+	flag.BoolVar(&debug, "debug", false, "start the provider with support for debuggers like delve")
+	flag.Parse()
+
+	err := providerserver.Serve(context.Background(), provider.New(version), providerserver.ServeOpts{
+		Address: "registry.terraform.io/nine-lives-later/qnap",
+		Debug:   debug,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
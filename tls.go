@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// buildTLSConfig creates the TLS configuration from the config options.
+func buildTLSConfig(options *ConfigOptions) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:   options.MinTLSVersion,
+		ServerName:   options.TLSServerName,
+		Certificates: options.ClientCertificates,
+	}
+
+	if config.MinVersion == 0 {
+		config.MinVersion = tls.VersionTLS12
+	}
+
+	// custom CA bundle
+	if options.CACertificateFile != "" || len(options.CACertificatesPEM) > 0 {
+		pool := x509.NewCertPool()
+
+		if len(options.CACertificatesPEM) > 0 && !pool.AppendCertsFromPEM(options.CACertificatesPEM) {
+			return nil, fmt.Errorf("failed to parse CA certificates: no certificate found")
+		}
+		if options.CACertificateFile != "" {
+			pem, err := os.ReadFile(options.CACertificateFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA certificate file: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("failed to parse CA certificate file %v: no certificate found", options.CACertificateFile)
+			}
+		}
+
+		config.RootCAs = pool
+	}
+
+	// pin the certificate of the NAS, e.g. a self-signed one
+	if options.CertificateFingerprint != "" {
+		fingerprint, err := parseCertificateFingerprint(options.CertificateFingerprint)
+		if err != nil {
+			return nil, err
+		}
+
+		config.InsecureSkipVerify = true // replaced by the fingerprint verification
+		config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return fmt.Errorf("no server certificate presented")
+			}
+
+			actual := sha256.Sum256(rawCerts[0])
+			if !bytes.Equal(actual[:], fingerprint) {
+				return fmt.Errorf("server certificate fingerprint mismatch: %v", hex.EncodeToString(actual[:]))
+			}
+			return nil
+		}
+
+		return config, nil
+	}
+
+	if options.IgnoreInvalidSSLCertificate {
+		config.InsecureSkipVerify = true
+	}
+
+	return config, nil
+}
+
+// parseCertificateFingerprint parses a SHA-256 fingerprint in hex format, with or without colons.
+func parseCertificateFingerprint(fingerprint string) ([]byte, error) {
+	raw, err := hex.DecodeString(strings.NewReplacer(":", "", " ", "").Replace(fingerprint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate fingerprint: %w", err)
+	}
+	if len(raw) != sha256.Size {
+		return nil, fmt.Errorf("failed to parse certificate fingerprint: expected SHA-256 (%v bytes), got %v bytes", sha256.Size, len(raw))
+	}
+
+	return raw, nil
+}
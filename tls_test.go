@@ -0,0 +1,68 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func createTestTLSServer() *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprint(w, "<QDocRoot><authPassed>1</authPassed><authSid>abcdef</authSid></QDocRoot>")
+	}))
+}
+
+func TestTLS_VerifyByDefault(t *testing.T) {
+	server := createTestTLSServer()
+	defer server.Close()
+
+	_, err := Connect(server.URL, "admin", "admin", nil)
+	if err == nil {
+		t.Fatal("Certificate error expected")
+	}
+}
+
+func TestTLS_CertificateFingerprint(t *testing.T) {
+	server := createTestTLSServer()
+	defer server.Close()
+
+	fingerprint := sha256.Sum256(server.Certificate().Raw)
+
+	_, err := Connect(server.URL, "admin", "admin", &ConfigOptions{
+		APICallTimeout:         5 * time.Second,
+		CertificateFingerprint: hex.EncodeToString(fingerprint[:]),
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect with pinned certificate: %v", err)
+	}
+
+	fingerprint[0]++
+
+	_, err = Connect(server.URL, "admin", "admin", &ConfigOptions{
+		APICallTimeout:         5 * time.Second,
+		CertificateFingerprint: hex.EncodeToString(fingerprint[:]),
+	})
+	if err == nil {
+		t.Fatal("Fingerprint mismatch expected")
+	}
+}
+
+func TestParseCertificateFingerprint(t *testing.T) {
+	_, err := parseCertificateFingerprint("AB:CD")
+	if err == nil {
+		t.Fatal("Error expected for short fingerprint")
+	}
+
+	raw, err := parseCertificateFingerprint("00:11:22:33:44:55:66:77:88:99:aa:bb:cc:dd:ee:ff:00:11:22:33:44:55:66:77:88:99:AA:BB:CC:DD:EE:FF")
+	if err != nil {
+		t.Fatalf("Failed to parse fingerprint: %v", err)
+	}
+	if len(raw) != sha256.Size {
+		t.Fatalf("Wrong fingerprint length: %v", len(raw))
+	}
+}
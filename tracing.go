@@ -0,0 +1,36 @@
+package manager
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/nine-lives-later/go-qnap-disk-manager"
+
+// startSpan creates a new span for the operation, as child of the span within the context.
+// The tracer provider of the ConfigOptions is used, or the global one, if not set.
+func (s *QnapSession) startSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	provider := otel.GetTracerProvider()
+	if s.options != nil && s.options.TracerProvider != nil {
+		provider = s.options.TracerProvider
+	}
+
+	attributes = append(attributes, attribute.String("server.address", s.host))
+
+	return provider.Tracer(tracerName).Start(ctx, "qnap."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...))
+}
+
+// endSpan ends the span and records the error, if any.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, redact(err.Error()))
+	}
+	span.End()
+}
@@ -0,0 +1,147 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing_ChildSpans(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+
+		switch {
+		case r.URL.Path == "/cgi-bin/authLogin.cgi":
+			fmt.Fprint(w, "<QDocRoot><authPassed>1</authPassed><authSid>abcdef</authSid></QDocRoot>")
+		case r.URL.Query().Get("store") == "poolList":
+			fmt.Fprint(w, "<QDocRoot><authPassed>1</authPassed><Pool_Index><row><poolID>1</poolID></row><row><poolID>2</poolID></row></Pool_Index><result>0</result></QDocRoot>")
+		default:
+			fmt.Fprintf(w, "<QDocRoot><authPassed>1</authPassed><Pool_Index><row><poolID>%v</poolID></row></Pool_Index><result>0</result></QDocRoot>", r.URL.Query().Get("poolID"))
+		}
+	}))
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	s, err := Connect(server.URL, "admin", "admin", &ConfigOptions{
+		APICallTimeout: 5 * time.Second,
+		TracerProvider: provider,
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	_, err = s.GetStoragePoolsContext(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to retrieve storage pools: %v", err)
+	}
+
+	// find the root span
+	var root sdktrace.ReadOnlySpan
+
+	for _, span := range recorder.Ended() {
+		if span.Name() == "qnap.GetStoragePools" {
+			root = span
+		}
+	}
+	if root == nil {
+		t.Fatal("Missing span for GetStoragePools")
+	}
+
+	// count the children
+	children := map[string]int{}
+
+	for _, span := range recorder.Ended() {
+		if span.Parent().SpanID() == root.SpanContext().SpanID() {
+			children[span.Name()]++
+		}
+	}
+
+	if children["qnap.request"] != 1 || children["qnap.getStoragePoolInfo"] != 2 {
+		t.Fatalf("Unexpected child spans: %v", children)
+	}
+}
+
+func TestTracing_RetryAndReloginSpans(t *testing.T) {
+	calls := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+
+		switch r.URL.Path {
+		case "/cgi-bin/authLogin.cgi":
+			fmt.Fprint(w, "<QDocRoot><authPassed>1</authPassed><authSid>abcdef</authSid></QDocRoot>")
+			return
+		case "/cgi-bin/authLogout.cgi", "/cgi-bin/management/manaRequest.cgi":
+			return
+		}
+
+		calls++
+		switch calls {
+		case 1: // transient error
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2: // session expired
+			fmt.Fprint(w, "<QDocRoot><authPassed>0</authPassed></QDocRoot>")
+		default:
+			fmt.Fprint(w, "<QDocRoot><authPassed>1</authPassed><iSCSITargetList></iSCSITargetList><result>0</result></QDocRoot>")
+		}
+	}))
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	s, err := Connect(server.URL, "admin", "admin", &ConfigOptions{
+		APICallTimeout: 5 * time.Second,
+		TracerProvider: provider,
+		RetryPolicy:    &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	_, err = s.GetISCSITargetsContext(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to retrieve iSCSI targets: %v", err)
+	}
+
+	spans := map[string][]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = append(spans[span.Name()], span)
+	}
+
+	if len(spans["qnap.Login"]) != 1 || len(spans["qnap.GetISCSITargets"]) != 1 || len(spans["qnap.relogin"]) != 1 {
+		t.Fatalf("Missing spans: %v", spans)
+	}
+	root := spans["qnap.GetISCSITargets"][0].SpanContext().SpanID()
+	relogin := spans["qnap.relogin"][0]
+
+	// all attempts and the re-login are children of the operation, never of an ended attempt
+	if relogin.Parent().SpanID() != root {
+		t.Fatal("Re-login is expected to be a child of the operation")
+	}
+
+	children := map[string]int{}
+	for _, span := range spans["qnap.request"] {
+		switch span.Parent().SpanID() {
+		case root:
+			children["operation"]++
+		case relogin.SpanContext().SpanID():
+			children["relogin"]++
+		case spans["qnap.Login"][0].SpanContext().SpanID():
+			children["login"]++
+		}
+	}
+
+	// 503, expired session and its repetition; logout and system info of the re-login
+	if children["operation"] != 3 || children["relogin"] != 2 || children["login"] != 1 {
+		t.Fatalf("Unexpected parents of the request spans: %v", children)
+	}
+}
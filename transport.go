@@ -0,0 +1,117 @@
+package manager
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// RequestMiddleware is called before every API request is sent to the NAS.
+// Returning an error aborts the request.
+type RequestMiddleware func(req *http.Request) error
+
+// ResponseMiddleware is called after every API response has been received from the NAS.
+// Returning an error fails the call.
+type ResponseMiddleware func(res *http.Response) error
+
+// newRestyClient creates the HTTP client for the communication with the NAS.
+func newRestyClient(host string, options *ConfigOptions) (*resty.Client, error) {
+	customTransport := options.HTTPClient != nil || options.Transport != nil
+
+	if customTransport && options.ProxyURL != "" {
+		return nil, fmt.Errorf("ProxyURL cannot be combined with a custom HTTPClient or Transport")
+	}
+
+	// create the client
+	var conn *resty.Client
+
+	if options.HTTPClient != nil {
+		// the client might be shared with other code, so never change it
+		client := *options.HTTPClient
+		conn = resty.NewWithClient(&client)
+	} else {
+		conn = resty.New()
+	}
+
+	conn.SetHostURL(host)
+
+	if options.APICallTimeout > 0 {
+		conn.SetTimeout(options.APICallTimeout)
+	}
+	if options.Transport != nil {
+		conn.SetTransport(options.Transport)
+	}
+
+	// setup SSL certificate handling and proxy (only for our own transport)
+	if !customTransport {
+		tlsConfig, err := buildTLSConfig(options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup TLS: %w", err)
+		}
+
+		conn.SetTLSClientConfig(tlsConfig)
+
+		if options.ProxyURL != "" {
+			conn.SetProxy(options.ProxyURL)
+
+			if !conn.IsProxySet() {
+				return nil, fmt.Errorf("failed to setup proxy: invalid proxy URL: %v", options.ProxyURL)
+			}
+		}
+	}
+
+	// never log any credentials or session IDs in debug mode
+	conn.OnRequestLog(func(l *resty.RequestLog) error {
+		l.Body = redact(l.Body)
+		return nil
+	})
+	conn.OnResponseLog(func(l *resty.ResponseLog) error {
+		l.Body = redact(l.Body)
+		return nil
+	})
+
+	// setup middlewares, wrapping the final transport
+	if len(options.RequestMiddlewares) > 0 {
+		conn.SetTransport(&middlewareTransport{
+			next:        conn.GetClient().Transport,
+			middlewares: options.RequestMiddlewares,
+		})
+	}
+	for _, m := range options.ResponseMiddlewares {
+		m := m
+
+		conn.OnAfterResponse(func(_ *resty.Client, res *resty.Response) error {
+			return m(res.RawResponse)
+		})
+	}
+
+	return conn, nil
+}
+
+// middlewareTransport calls the request middlewares before passing the request to the next transport.
+type middlewareTransport struct {
+	next        http.RoundTripper
+	middlewares []RequestMiddleware
+}
+
+func (t *middlewareTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a transport must not modify the original request
+	req = req.Clone(req.Context())
+
+	for _, m := range t.middlewares {
+		if err := m(req); err != nil {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, err
+		}
+	}
+
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return next.RoundTrip(req)
+}
@@ -0,0 +1,103 @@
+package manager
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type countingTransport struct {
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestConnect_CustomTransportAndMiddlewares(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Trace-ID") != "unittest" {
+			t.Errorf("Missing header set by request middleware")
+		}
+
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprint(w, "<QDocRoot><authPassed>1</authPassed><authSid>abcdef</authSid></QDocRoot>")
+	}))
+	defer server.Close()
+
+	transport := &countingTransport{}
+	responses := 0
+
+	_, err := Connect(server.URL, "admin", "admin", &ConfigOptions{
+		Transport: transport,
+		RequestMiddlewares: []RequestMiddleware{
+			func(req *http.Request) error {
+				req.Header.Set("X-Trace-ID", "unittest")
+				return nil
+			},
+		},
+		ResponseMiddlewares: []ResponseMiddleware{
+			func(res *http.Response) error {
+				responses++
+				return nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	if transport.requests != 2 { // login and system information
+		t.Fatalf("Custom transport not used: %v requests", transport.requests)
+	}
+	if responses != 2 {
+		t.Fatalf("Response middleware not called: %v responses", responses)
+	}
+}
+
+func TestConnect_SharedHTTPClientUnchanged(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-First") != "1" || r.Header.Get("X-Second") != "2" {
+			t.Errorf("Missing headers set by request middlewares: %v", r.Header)
+		}
+
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprint(w, "<QDocRoot><authPassed>1</authPassed><authSid>abcdef</authSid></QDocRoot>")
+	}))
+	defer server.Close()
+
+	client := &http.Client{}
+
+	_, err := Connect(server.URL, "admin", "admin", &ConfigOptions{
+		APICallTimeout: 5 * time.Second,
+		HTTPClient:     client,
+		RequestMiddlewares: []RequestMiddleware{
+			func(req *http.Request) error {
+				req.Header.Set("X-First", "1")
+				return nil
+			},
+			func(req *http.Request) error {
+				req.Header.Set("X-Second", "2")
+				return nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	if client.Timeout != 0 || client.Transport != nil || client.Jar != nil {
+		t.Fatalf("Shared HTTP client must not be changed: %+v", client)
+	}
+}
+
+func TestConnect_ProxyWithCustomTransport(t *testing.T) {
+	_, err := Connect("localhost", "admin", "admin", &ConfigOptions{
+		Transport: http.DefaultTransport,
+		ProxyURL:  "http://proxy:3128",
+	})
+	if err == nil {
+		t.Fatal("Error expected")
+	}
+}
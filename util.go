@@ -1,8 +1,56 @@
 package manager
 
+import (
+	"context"
+	"regexp"
+	"time"
+)
+
+// sensitiveParams lists the parameters and XML elements whose values must never be exposed.
+var sensitiveParams = []string{"pwd", "sid", "authSid", "qtoken", "security_code", "CHAPPasswd", "CHAPSecret", "MutualCHAPPasswd", "MutualCHAPSecret"}
+
+var redactPatterns = buildRedactPatterns(sensitiveParams)
+
+func buildRedactPatterns(names []string) []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, 0, len(names)*2)
+
+	for _, name := range names {
+		quoted := regexp.QuoteMeta(name)
+
+		patterns = append(patterns,
+			regexp.MustCompile(`(?i)(\b`+quoted+`=)[^&\s"]*`),                      // query strings and form bodies
+			regexp.MustCompile(`(?is)(<`+quoted+`>)(?:<!\[CDATA\[.*?\]\]>|[^<]*)`), // XML elements
+		)
+	}
+
+	return patterns
+}
+
+// redact masks all credentials and session IDs within the text,
+// e.g. before returning a response body as part of an error message.
+func redact(text string) string {
+	for _, pattern := range redactPatterns {
+		text = pattern.ReplaceAllString(text, "${1}***")
+	}
+	return text
+}
+
 func boolToIntStr(b bool) string {
 	if b {
 		return "1"
 	}
 	return "0"
 }
+
+// sleepContext waits for the duration or until the context is done.
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
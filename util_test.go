@@ -0,0 +1,19 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	input := "user=admin&pwd=YWRtaW4=&sid=abcdef <authSid><![CDATA[abcdef]]></authSid><qtoken>12345</qtoken><username>admin</username>"
+
+	output := redact(input)
+
+	if strings.Contains(output, "YWRtaW4") || strings.Contains(output, "abcdef") || strings.Contains(output, "12345") {
+		t.Fatalf("Secrets not redacted: %v", output)
+	}
+	if !strings.Contains(output, "user=admin") || !strings.Contains(output, "<username>admin</username>") {
+		t.Fatalf("Too much redacted: %v", output)
+	}
+}
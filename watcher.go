@@ -0,0 +1,386 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+type WatchEventType string
+
+const (
+	WatchEventType_Added    WatchEventType = "added"
+	WatchEventType_Modified WatchEventType = "modified"
+	WatchEventType_Deleted  WatchEventType = "deleted"
+	WatchEventType_Resync   WatchEventType = "resync" // periodic re-delivery of an unchanged object
+)
+
+type WatchObjectKind string
+
+const (
+	WatchObjectKind_LUN         WatchObjectKind = "lun"
+	WatchObjectKind_ISCSITarget WatchObjectKind = "iscsi_target"
+	WatchObjectKind_StoragePool WatchObjectKind = "storage_pool"
+)
+
+// WatchEvent is a change of a LUN, iSCSI target or storage pool detected by the Watcher.
+// Only the fields matching the kind are set: the new object on addition, modification and resync,
+// the old object on modification, deletion and resync.
+type WatchEvent struct {
+	Type WatchEventType
+	Kind WatchObjectKind
+
+	LUN    *LUN
+	OldLUN *LUN
+
+	Target    *ISCSITarget
+	OldTarget *ISCSITarget
+
+	Pool    *StoragePool
+	OldPool *StoragePool
+}
+
+// ErrWatcherStarted is returned when starting a watcher a second time.
+var ErrWatcherStarted = errors.New("watcher already started")
+
+// WatcherOptions contains the settings of a watcher.
+type WatcherOptions struct {
+	Interval       time.Duration   // time between two polls, defaults to 10 seconds
+	ResyncInterval time.Duration   // re-deliver all unchanged objects as resync events, zero to disable
+	InitialBackoff time.Duration   // wait time after the first failed poll, doubled on every further failure; defaults to the interval
+	MaxBackoff     time.Duration   // upper limit of the wait time, defaults to 5 minutes
+	BufferSize     int             // size of the event channel, defaults to 100
+	SkipPools      bool            // do not watch the storage pools, saves one API call per pool
+	OnError        func(err error) // called on every failed poll, the cache is kept until the next successful one
+}
+
+var defaultWatcherOptions = WatcherOptions{
+	Interval:   10 * time.Second,
+	MaxBackoff: 5 * time.Minute,
+	BufferSize: 100,
+}
+
+// Watcher polls the LUNs, iSCSI targets and storage pools of a QNAP system, keeps them
+// in a local cache and emits an event for every change, similar to a Kubernetes informer.
+// The cache is safe for concurrent use by multiple goroutines.
+type Watcher struct {
+	reader  Reader
+	options WatcherOptions
+	events  chan WatchEvent
+
+	lock       sync.RWMutex // guards the cache below
+	started    bool
+	synced     bool
+	luns       map[int]*LUN
+	lunsByName map[string]*LUN
+	lunsByNAA  map[string]*LUN
+	targets    map[int]*ISCSITarget
+	pools      map[int]*StoragePool
+}
+
+// NewWatcher creates a new watcher polling the reader, e.g. a *QnapSession or *SessionPool.
+// The watcher has to be started by Run().
+func NewWatcher(reader Reader, options *WatcherOptions) *Watcher {
+	o := defaultWatcherOptions
+	if options != nil {
+		o = *options
+
+		if o.Interval <= 0 {
+			o.Interval = defaultWatcherOptions.Interval
+		}
+		if o.MaxBackoff <= 0 {
+			o.MaxBackoff = defaultWatcherOptions.MaxBackoff
+		}
+		if o.BufferSize <= 0 {
+			o.BufferSize = defaultWatcherOptions.BufferSize
+		}
+	}
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = o.Interval
+	}
+
+	return &Watcher{
+		reader:     reader,
+		options:    o,
+		events:     make(chan WatchEvent, o.BufferSize),
+		luns:       map[int]*LUN{},
+		lunsByName: map[string]*LUN{},
+		lunsByNAA:  map[string]*LUN{},
+		targets:    map[int]*ISCSITarget{},
+		pools:      map[int]*StoragePool{},
+	}
+}
+
+// Events returns the channel receiving the changes. It is closed when Run() returns.
+// The watcher blocks while the channel is full, so it has to be consumed continuously.
+func (w *Watcher) Events() <-chan WatchEvent {
+	return w.events
+}
+
+// Run polls the QNAP system until the context is canceled. All objects found by
+// the first successful poll are emitted as added events. A watcher can only be run once.
+func (w *Watcher) Run(ctx context.Context) error {
+	w.lock.Lock()
+	if w.started {
+		w.lock.Unlock()
+		return ErrWatcherStarted
+	}
+	w.started = true
+	w.lock.Unlock()
+
+	defer close(w.events)
+
+	backoff := time.Duration(0)
+	lastResync := time.Now()
+
+	for {
+		if err := w.poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			if w.options.OnError != nil {
+				w.options.OnError(err)
+			}
+
+			// wait longer on every failure
+			if backoff == 0 {
+				backoff = w.options.InitialBackoff
+			} else {
+				backoff *= 2
+				if backoff > w.options.MaxBackoff {
+					backoff = w.options.MaxBackoff
+				}
+			}
+		} else {
+			backoff = 0
+
+			if w.options.ResyncInterval > 0 && time.Since(lastResync) >= w.options.ResyncInterval {
+				if err := w.resync(ctx); err != nil {
+					return err
+				}
+				lastResync = time.Now()
+			}
+		}
+
+		wait := w.options.Interval
+		if backoff > 0 {
+			wait = backoff
+		}
+
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// HasSynced returns true after the first successful poll.
+func (w *Watcher) HasSynced() bool {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	return w.synced
+}
+
+// GetLUNByIndex returns the cached LUN by its index, nil if unknown.
+func (w *Watcher) GetLUNByIndex(lunIndex int) *LUN {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	return w.luns[lunIndex]
+}
+
+// GetLUNByName returns the cached LUN by its name, nil if unknown.
+func (w *Watcher) GetLUNByName(name string) *LUN {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	return w.lunsByName[name]
+}
+
+// GetLUNByNAA returns the cached LUN by its NAA identifier, nil if unknown.
+func (w *Watcher) GetLUNByNAA(naa string) *LUN {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	return w.lunsByNAA[naa]
+}
+
+// GetISCSITargetByIndex returns the cached iSCSI target by its index, nil if unknown.
+func (w *Watcher) GetISCSITargetByIndex(targetIndex int) *ISCSITarget {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	return w.targets[targetIndex]
+}
+
+// LUNs returns all cached LUNs, ordered by index.
+func (w *Watcher) LUNs() []*LUN {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	luns := make([]*LUN, 0, len(w.luns))
+	for _, lun := range w.luns {
+		luns = append(luns, lun)
+	}
+	sort.Slice(luns, func(i, j int) bool { return luns[i].LUNIndex < luns[j].LUNIndex })
+	return luns
+}
+
+// ISCSITargets returns all cached iSCSI targets, ordered by index.
+func (w *Watcher) ISCSITargets() []*ISCSITarget {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	targets := make([]*ISCSITarget, 0, len(w.targets))
+	for _, target := range w.targets {
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].TargetIndex < targets[j].TargetIndex })
+	return targets
+}
+
+// StoragePools returns all cached storage pools, ordered by ID.
+func (w *Watcher) StoragePools() []*StoragePool {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	pools := make([]*StoragePool, 0, len(w.pools))
+	for _, pool := range w.pools {
+		pools = append(pools, pool)
+	}
+	sort.Slice(pools, func(i, j int) bool { return pools[i].PoolID < pools[j].PoolID })
+	return pools
+}
+
+// poll retrieves the current state, updates the cache and emits the changes.
+func (w *Watcher) poll(ctx context.Context) error {
+	luns, err := w.reader.GetLUNsContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve LUNs: %w", err)
+	}
+	targets, err := w.reader.GetISCSITargetsContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve iSCSI targets: %w", err)
+	}
+
+	var pools []*StoragePool
+	if !w.options.SkipPools {
+		if pools, err = w.reader.GetStoragePoolsContext(ctx); err != nil {
+			return fmt.Errorf("failed to retrieve storage pools: %w", err)
+		}
+	}
+
+	// update the cache, and collect the changes
+	var events []WatchEvent
+
+	w.lock.Lock()
+
+	newLUNs := make(map[int]*LUN, len(luns))
+	for _, lun := range luns {
+		newLUNs[lun.LUNIndex] = lun
+	}
+	events = diffObjects(events, WatchObjectKind_LUN, w.luns, newLUNs, func(e *WatchEvent, old, new *LUN) { e.OldLUN, e.LUN = old, new })
+
+	w.luns = newLUNs
+	w.lunsByName = make(map[string]*LUN, len(luns))
+	w.lunsByNAA = make(map[string]*LUN, len(luns))
+	for _, lun := range luns {
+		w.lunsByName[lun.LUNName] = lun
+		if lun.LUNNAA != "" {
+			w.lunsByNAA[lun.LUNNAA] = lun
+		}
+	}
+
+	newTargets := make(map[int]*ISCSITarget, len(targets))
+	for _, target := range targets {
+		newTargets[target.TargetIndex] = target
+	}
+	events = diffObjects(events, WatchObjectKind_ISCSITarget, w.targets, newTargets, func(e *WatchEvent, old, new *ISCSITarget) { e.OldTarget, e.Target = old, new })
+	w.targets = newTargets
+
+	if !w.options.SkipPools {
+		newPools := make(map[int]*StoragePool, len(pools))
+		for _, pool := range pools {
+			newPools[pool.PoolID] = pool
+		}
+		events = diffObjects(events, WatchObjectKind_StoragePool, w.pools, newPools, func(e *WatchEvent, old, new *StoragePool) { e.OldPool, e.Pool = old, new })
+		w.pools = newPools
+	}
+
+	w.synced = true
+
+	w.lock.Unlock()
+
+	return w.emit(ctx, events)
+}
+
+// resync emits all cached objects as resync events.
+func (w *Watcher) resync(ctx context.Context) error {
+	var events []WatchEvent
+
+	for _, lun := range w.LUNs() {
+		events = append(events, WatchEvent{Type: WatchEventType_Resync, Kind: WatchObjectKind_LUN, LUN: lun, OldLUN: lun})
+	}
+	for _, target := range w.ISCSITargets() {
+		events = append(events, WatchEvent{Type: WatchEventType_Resync, Kind: WatchObjectKind_ISCSITarget, Target: target, OldTarget: target})
+	}
+	for _, pool := range w.StoragePools() {
+		events = append(events, WatchEvent{Type: WatchEventType_Resync, Kind: WatchObjectKind_StoragePool, Pool: pool, OldPool: pool})
+	}
+
+	return w.emit(ctx, events)
+}
+
+func (w *Watcher) emit(ctx context.Context, events []WatchEvent) error {
+	for _, event := range events {
+		select {
+		case w.events <- event:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// diffObjects appends the events of the changes between the old and new objects, ordered by key.
+func diffObjects[T any](events []WatchEvent, kind WatchObjectKind, old, new map[int]*T, set func(e *WatchEvent, old, new *T)) []WatchEvent {
+	keys := make([]int, 0, len(old)+len(new))
+	for key := range new {
+		keys = append(keys, key)
+	}
+	for key := range old {
+		if _, ok := new[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Ints(keys)
+
+	for _, key := range keys {
+		o, n := old[key], new[key]
+
+		e := WatchEvent{Kind: kind}
+
+		switch {
+		case o == nil:
+			e.Type = WatchEventType_Added
+			set(&e, nil, n)
+		case n == nil:
+			e.Type = WatchEventType_Deleted
+			set(&e, o, nil)
+		case !reflect.DeepEqual(o, n):
+			e.Type = WatchEventType_Modified
+			set(&e, o, n)
+		default:
+			continue
+		}
+
+		events = append(events, e)
+	}
+
+	return events
+}
@@ -0,0 +1,157 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testReader returns the scripted state, or the error if set.
+type testReader struct {
+	lock    sync.Mutex
+	luns    []*LUN
+	targets []*ISCSITarget
+	err     error
+	polls   int
+	ctx     context.Context // of the last poll
+}
+
+func (r *testReader) set(luns []*LUN, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.luns, r.err = luns, err
+}
+
+func (r *testReader) GetSystemInfo() (*SystemInfo, error)      { return &SystemInfo{}, nil }
+func (r *testReader) GetStoragePools() ([]*StoragePool, error) { return nil, nil }
+func (r *testReader) GetLUNByIndex(lunIndex int) (*LUN, error) { return nil, nil }
+func (r *testReader) GetLUNs() ([]*LUN, error) {
+	return r.GetLUNsContext(context.Background())
+}
+
+func (r *testReader) GetISCSITargets() ([]*ISCSITarget, error) {
+	return r.GetISCSITargetsContext(context.Background())
+}
+
+func (r *testReader) GetSystemInfoContext(ctx context.Context) (*SystemInfo, error) {
+	return r.GetSystemInfo()
+}
+
+func (r *testReader) GetStoragePoolsContext(ctx context.Context) ([]*StoragePool, error) {
+	return r.GetStoragePools()
+}
+
+func (r *testReader) GetLUNByIndexContext(ctx context.Context, lunIndex int) (*LUN, error) {
+	return r.GetLUNByIndex(lunIndex)
+}
+
+func (r *testReader) GetLUNsContext(ctx context.Context) ([]*LUN, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.polls++
+	r.ctx = ctx
+	return r.luns, r.err
+}
+
+func (r *testReader) GetISCSITargetsContext(ctx context.Context) ([]*ISCSITarget, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.targets, nil
+}
+
+func nextEvent(t *testing.T, w *Watcher) WatchEvent {
+	select {
+	case e := <-w.Events():
+		return e
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for event")
+		return WatchEvent{}
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	reader := &testReader{
+		luns:    []*LUN{{LUNIndex: 1, LUNName: "data01", LUNNAA: "naa1"}},
+		targets: []*ISCSITarget{{TargetIndex: 0, TargetName: "k8s"}},
+	}
+
+	var errs []error
+	w := NewWatcher(reader, &WatcherOptions{Interval: 10 * time.Millisecond, SkipPools: true, OnError: func(err error) { errs = append(errs, err) }})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+
+	if e := nextEvent(t, w); e.Type != WatchEventType_Added || e.Kind != WatchObjectKind_LUN || e.LUN.LUNName != "data01" {
+		t.Fatalf("Wrong LUN event: %+v", e)
+	}
+	if e := nextEvent(t, w); e.Type != WatchEventType_Added || e.Kind != WatchObjectKind_ISCSITarget || e.Target.TargetName != "k8s" {
+		t.Fatalf("Wrong target event: %+v", e)
+	}
+	if !w.HasSynced() || w.GetLUNByName("data01") == nil || w.GetLUNByNAA("naa1") == nil || w.GetISCSITargetByIndex(0) == nil {
+		t.Fatalf("Cache not filled")
+	}
+
+	// modification
+	reader.set([]*LUN{{LUNIndex: 1, LUNName: "data01", LUNNAA: "naa1", LUNStatus: 1}}, nil)
+
+	if e := nextEvent(t, w); e.Type != WatchEventType_Modified || e.OldLUN.LUNStatus != 0 || e.LUN.LUNStatus != 1 {
+		t.Fatalf("Wrong modification event: %+v", e)
+	}
+
+	// errors keep the cache
+	reader.set(nil, errors.New("scripted error"))
+	time.Sleep(50 * time.Millisecond)
+
+	if w.GetLUNByIndex(1) == nil {
+		t.Fatalf("Cache cleared on error")
+	}
+
+	// deletion
+	reader.set([]*LUN{}, nil)
+
+	if e := nextEvent(t, w); e.Type != WatchEventType_Deleted || e.OldLUN.LUNIndex != 1 || e.LUN != nil {
+		t.Fatalf("Wrong deletion event: %+v", e)
+	}
+	if w.GetLUNByName("data01") != nil || w.GetLUNByNAA("naa1") != nil {
+		t.Fatalf("Deleted LUN still cached")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := <-w.Events(); ok {
+		t.Fatalf("Event channel not closed")
+	}
+	if len(errs) == 0 {
+		t.Fatalf("OnError not called")
+	}
+	if err := w.Run(context.Background()); !errors.Is(err, ErrWatcherStarted) {
+		t.Fatalf("ErrWatcherStarted expected: %v", err)
+	}
+}
+
+func TestWatcher_Backoff(t *testing.T) {
+	reader := &testReader{err: errors.New("scripted error")}
+
+	w := NewWatcher(reader, &WatcherOptions{Interval: time.Millisecond, InitialBackoff: 20 * time.Millisecond, MaxBackoff: 40 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+
+	w.Run(ctx)
+
+	// 0, 20, 60, 100, 140 ms instead of every millisecond
+	if reader.polls > 6 {
+		t.Fatalf("No backoff on errors: %v polls", reader.polls)
+	}
+	if reader.ctx != ctx {
+		t.Fatal("Context of Run not passed to the reader")
+	}
+}
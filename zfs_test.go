@@ -0,0 +1,93 @@
+package manager_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	manager "github.com/nine-lives-later/go-qnap-disk-manager"
+	"github.com/nine-lives-later/go-qnap-disk-manager/qnapfake"
+)
+
+func TestCreateBlockBasedLUNEx_ZFSOptionsOnQuTSHero(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+	fake.SetFirmwareVersion("h5.1.0")
+
+	var lock sync.Mutex
+	var query, form url.Values
+
+	fake.SetInterceptor(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/cgi-bin/disk/iscsi_lun_setting.cgi" && r.URL.Query().Get("func") == "add_lun" {
+			r.ParseForm()
+
+			lock.Lock()
+			query, form = r.URL.Query(), r.PostForm
+			lock.Unlock()
+		}
+		return false
+	})
+
+	s := connectFake(t, fake)
+
+	_, err := s.CreateBlockBasedLUNEx(1, "data01", 10, manager.LUNAllocateMode_Thin, false, 80, &manager.ZFSLUNOptions{
+		Compression:   true,
+		Deduplication: false,
+		BlockSizeKB:   64,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create LUN: %v", err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	expected := map[string]string{
+		"backend":         "zfs",
+		"compression":     "1",
+		"dedup":           "0",
+		"block_size":      "64",
+		"LUNName":         "data01",
+		"LUNCapacity":     "10",
+		"poolID":          "1",
+		"LUNThinAllocate": "1",
+	}
+	for key, value := range expected {
+		if query.Get(key) != value {
+			t.Errorf("Query parameter %v: expected '%v', got '%v'", key, value, query.Get(key))
+		}
+	}
+	if form.Get("sid") == "" || query.Get("sid") != "" {
+		t.Errorf("Session ID expected in the form only: query %v, form %v", query, form)
+	}
+}
+
+func TestCreateBlockBasedLUNEx_ZFSOptionsOnUnknownFirmware(t *testing.T) {
+	fake := qnapfake.NewServer("admin", "admin")
+	defer fake.Close()
+	fake.SetFirmwareVersion("")
+
+	var output bytes.Buffer
+
+	s := connectFakeWithOptions(t, fake, &manager.ConfigOptions{
+		APICallTimeout: 5 * time.Second,
+		Logger:         slog.New(slog.NewTextHandler(&output, &slog.HandlerOptions{Level: slog.LevelWarn})),
+	})
+
+	if !strings.Contains(output.String(), "firmware version unknown") {
+		t.Errorf("Warning expected for the unknown firmware, got: %v", output.String())
+	}
+
+	_, err := s.CreateBlockBasedLUNEx(1, "data01", 10, manager.LUNAllocateMode_Thin, false, 80, &manager.ZFSLUNOptions{Compression: true})
+	if err == nil || !strings.Contains(err.Error(), "firmware version is unknown") {
+		t.Fatalf("Error expected for the unknown firmware, got: %v", err)
+	}
+	if len(fake.LUNs()) != 0 {
+		t.Fatal("LUN created without a known firmware")
+	}
+}